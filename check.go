@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+// checkProblem is one finding of `efx-doc check`
+type checkProblem struct {
+	Severity string `json:"severity"` // "error", or "warning" for orphaned files
+	Kind     string `json:"kind"`     // schema, unresolved, duplicate, orphan, broken-link or missing-image
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats the problem like a compiler message, "file:line: severity: message"
+func (p checkProblem) String() string {
+	where := p.File
+	if p.Line > 0 {
+		where += ":" + strconv.Itoa(p.Line)
+	}
+	if where == "" {
+		return p.Severity + ": " + p.Message
+	}
+	return where + ": " + p.Severity + ": " + p.Message
+}
+
+// checkReport sums up the integrity of a workspace
+type checkReport struct {
+	Workspace string         `json:"workspace"`
+	Errors    int            `json:"errors"`
+	Warnings  int            `json:"warnings"`
+	Problems  []checkProblem `json:"problems"`
+}
+
+func (r *checkReport) add(p checkProblem) {
+	if p.Severity == "warning" {
+		r.Warnings++
+	} else {
+		r.Errors++
+	}
+	r.Problems = append(r.Problems, p)
+}
+
+// manifestSchema lists the keys allowed at each level of docs.yaml
+var manifestSchema = map[string][]string{
+	"docs.yaml": {"name", "description", "discover", "raw_html", "categories"},
+	"category":  {"name", "folder", "references", "categories"},
+	"reference": {"name", "description", "file"},
+}
+
+// checkWorkspace validates docs.yaml, then every reference, file and
+// relative link of the workspace. Paths in the report are relative to dataDir.
+func checkWorkspace(name, dataDir string) checkReport {
+	report := checkReport{Workspace: name, Problems: []checkProblem{}}
+
+	// Schema first: a manifest that doesn't load stops the other checks
+	refLines := map[string][]int{} // Lines of each itemKey, repeated names in file order
+	manifest := ""                 // Where references are declared, empty when discovered
+	data, err := os.ReadFile(filepath.Join(dataDir, docsConfigName))
+	if err == nil {
+		manifest = docsConfigName
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			report.add(checkProblem{Severity: "error", Kind: "schema", File: docsConfigName, Line: yamlErrorLine(err), Message: err.Error()})
+			return report
+		}
+		if len(root.Content) > 0 {
+			checkManifestNode(&report, root.Content[0], "docs.yaml", nil, refLines)
+			sort.SliceStable(report.Problems, func(i, j int) bool { return report.Problems[i].Line < report.Problems[j].Line })
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		report.add(checkProblem{Severity: "error", Kind: "schema", File: docsConfigName, Message: err.Error()})
+		return report
+	}
+
+	config, err := loadDocsConfig(dataDir)
+	if err != nil {
+		if report.Errors > 0 {
+			return report // Already explained by the schema problems
+		}
+		report.add(checkProblem{Severity: "error", Kind: "schema", File: docsConfigName, Line: yamlErrorLine(err), Message: err.Error()})
+		return report
+	}
+
+	// References: each resolves to a file, and names are unique within a
+	// category as references are looked up by category and name
+	referenced := map[string]bool{}
+	seen := map[string]bool{}
+	occurrences := map[string]int{}
+	for _, node := range config.walkCategories() {
+		for _, ref := range node.References {
+			if strings.TrimSpace(ref.Name) == "" {
+				continue // A schema problem already
+			}
+			key := itemKey(node.Key(), ref.Name)
+			line := 0
+			if lines := refLines[key]; occurrences[key] < len(lines) {
+				line = lines[occurrences[key]]
+			}
+			occurrences[key]++
+			if slugKey := itemKey(node.Key(), slugify(ref.Name)); seen[slugKey] {
+				report.add(checkProblem{Severity: "error", Kind: "duplicate", File: manifest, Line: line,
+					Message: fmt.Sprintf("reference %q appears more than once in %q", ref.Name, node.Key())})
+			} else {
+				seen[slugKey] = true
+			}
+
+			file, err := resolveDoc(config, dataDir, node.Key(), ref.Name)
+			if err != nil {
+				report.add(checkProblem{Severity: "error", Kind: "unresolved", File: manifest, Line: line, Message: err.Error()})
+				continue
+			}
+			if rel, err := filepath.Rel(dataDir, file); err == nil {
+				referenced[filepath.ToSlash(rel)] = true
+			}
+		}
+	}
+
+	files, err := markdownFiles(dataDir)
+	if err != nil {
+		report.add(checkProblem{Severity: "error", Kind: "orphan", Message: err.Error()})
+		return report
+	}
+	md := newWebMarkdown()
+	for _, rel := range files {
+		if !referenced[rel] && rel != "README.md" {
+			report.add(checkProblem{Severity: "warning", Kind: "orphan", File: rel, Message: "not referenced by any category"})
+		}
+		source, err := os.ReadFile(filepath.Join(dataDir, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		checkLinks(&report, dataDir, rel, source, md.Parser().Parse(text.NewReader(source)))
+	}
+	return report
+}
+
+// checkManifestNode validates one mapping of docs.yaml against manifestSchema,
+// recording the lines of each reference under its itemKey
+func checkManifestNode(report *checkReport, node *yaml.Node, level string, catPath []string, refLines map[string][]int) {
+	schemaError := func(n *yaml.Node, format string, args ...any) {
+		report.add(checkProblem{Severity: "error", Kind: "schema", File: docsConfigName, Line: n.Line, Message: fmt.Sprintf(format, args...)})
+	}
+	if node.Kind != yaml.MappingNode {
+		schemaError(node, "%s entry must be a mapping", level)
+		return
+	}
+
+	// Keys in file order, so problems come out in a stable order
+	var keys []string
+	fields := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		allowed := false
+		for _, name := range manifestSchema[level] {
+			allowed = allowed || name == key.Value
+		}
+		if !allowed {
+			schemaError(key, "unknown field %q in %s (allowed: %s)", key.Value, level, strings.Join(manifestSchema[level], ", "))
+			continue
+		}
+		keys = append(keys, key.Value)
+		fields[key.Value] = value
+	}
+
+	name := ""
+	if value, ok := fields["name"]; ok {
+		name = value.Value
+	}
+	if level != "docs.yaml" && strings.TrimSpace(name) == "" {
+		schemaError(node, "%s without a name", level)
+	}
+
+	for _, key := range keys {
+		value := fields[key]
+		switch key {
+		case "categories", "references":
+			if value.Kind != yaml.SequenceNode {
+				schemaError(value, "%s must be a list", key)
+				continue
+			}
+			childPath := catPath
+			if level == "category" {
+				childPath = append(append([]string{}, catPath...), name)
+			}
+			for _, child := range value.Content {
+				if key == "categories" {
+					checkManifestNode(report, child, "category", childPath, refLines)
+				} else {
+					checkManifestNode(report, child, "reference", childPath, refLines)
+				}
+			}
+		case "discover":
+			if value.Kind != yaml.ScalarNode || value.Tag != "!!bool" {
+				schemaError(value, "discover must be true or false")
+			}
+		case "raw_html":
+			if mode := strings.ToLower(value.Value); mode != rawHTMLOmit && mode != rawHTMLSanitize && mode != rawHTMLAllow {
+				schemaError(value, "raw_html must be %s, %s or %s", rawHTMLOmit, rawHTMLSanitize, rawHTMLAllow)
+			}
+		case "file", "folder":
+			// file is relative to the workspace root, folder to the parent category
+			clean := path.Clean(filepath.ToSlash(value.Value))
+			if value.Kind != yaml.ScalarNode {
+				schemaError(value, "%s must be a path", key)
+			} else if path.IsAbs(clean) || filepath.IsAbs(value.Value) || (key == "file" && (clean == ".." || strings.HasPrefix(clean, "../"))) {
+				schemaError(value, "%s %q must stay inside the workspace", key, value.Value)
+			}
+		default:
+			if value.Kind != yaml.ScalarNode {
+				schemaError(value, "%s must be text", key)
+			}
+		}
+	}
+
+	if level == "reference" && name != "" {
+		key := itemKey(strings.Join(catPath, categoryPathSep), name)
+		refLines[key] = append(refLines[key], node.Line)
+	}
+}
+
+// checkLinks reports relative links and images of a document whose target
+// doesn't exist, or sits outside the workspace
+func checkLinks(report *checkReport, dataDir, rel string, source []byte, doc ast.Node) {
+	var problems []checkProblem
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest, kind, what string
+		switch n := n.(type) {
+		case *ast.Link:
+			dest, kind, what = string(n.Destination), "broken-link", "broken link"
+		case *ast.Image:
+			dest, kind, what = string(n.Destination), "missing-image", "missing image"
+		default:
+			return ast.WalkContinue, nil
+		}
+		if !isRelativeLink(dest) {
+			return ast.WalkContinue, nil
+		}
+		raw, _ := splitLink(dest)
+		target, err := url.PathUnescape(raw)
+		if err != nil {
+			target = raw
+		}
+		full := filepath.Join(dataDir, filepath.FromSlash(path.Dir(rel)), filepath.FromSlash(target))
+
+		message := ""
+		if _, err := os.Stat(full); err != nil {
+			message = fmt.Sprintf("%s %s", what, dest)
+		} else if !insideDir(dataDir, full) {
+			message = fmt.Sprintf("%s points outside the workspace", dest)
+		}
+		if message != "" {
+			problems = append(problems, checkProblem{Severity: "error", Kind: kind, File: rel, Line: nodeLine(n, source), Message: message})
+		}
+		return ast.WalkContinue, nil
+	})
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	for _, p := range problems {
+		report.add(p)
+	}
+}
+
+// nodeLine finds the 1-based source line of an inline node from its first
+// text segment, or from the lines of the block holding it
+func nodeLine(n ast.Node, source []byte) int {
+	offset := -1
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for p := n; offset < 0 && p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			offset = p.Lines().At(0).Start
+		}
+	}
+	if offset < 0 {
+		return 0
+	}
+	return bytes.Count(source[:offset], []byte("\n")) + 1
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine extracts the line number of a yaml.v3 error, 0 when it has none
+func yamlErrorLine(err error) int {
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line
+	}
+	return 0
+}
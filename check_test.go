@@ -0,0 +1,150 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// assertProblems compares a report with the problems expected, in order
+func assertProblems(t *testing.T, report checkReport, want []checkProblem) {
+	t.Helper()
+	if !reflect.DeepEqual(report.Problems, want) {
+		t.Errorf("problems:\n got %v\nwant %v", report.Problems, want)
+	}
+	errors, warnings := 0, 0
+	for _, p := range want {
+		if p.Severity == "warning" {
+			warnings++
+		} else {
+			errors++
+		}
+	}
+	if report.Errors != errors || report.Warnings != warnings {
+		t.Errorf("counts = %d error(s) %d warning(s), want %d and %d", report.Errors, report.Warnings, errors, warnings)
+	}
+}
+
+func TestCheckWorkspaceClean(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"docs.yaml": `name: Clean
+raw_html: sanitize
+categories:
+  - name: API
+    folder: api
+    references:
+      - name: Button
+        description: A button
+  - name: Guides
+    folder: guides
+    references:
+      - name: Button
+      - name: Start
+        file: start.md
+`,
+		"README.md":        "# Readme\n[api](api/Button.md)\n",
+		"api/Button.md":    "# Button\n![logo](logo.png) [start](../start.md#top) [web](https://example.com) [here](#usage)\n",
+		"api/logo.png":     "png",
+		"guides/Button.md": "# Button\n",
+		"start.md":         "# Start\n",
+		"_drafts/Draft.md": "# Draft, skipped like discovery does\n",
+	})
+	assertProblems(t, checkWorkspace("clean", root), []checkProblem{})
+}
+
+func TestCheckWorkspaceSchema(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"docs.yaml": `name: Check
+colour: blue
+discover: yes
+raw_html: maybe
+categories:
+  - name: API
+    folder: /api
+    references:
+      - name: Button
+        file: ../x.md
+      - description: no name
+      - Link
+`,
+	})
+	assertProblems(t, checkWorkspace("check", root), []checkProblem{
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 2, Message: `unknown field "colour" in docs.yaml (allowed: name, description, discover, raw_html, categories)`},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 3, Message: "discover must be true or false"},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 4, Message: "raw_html must be omit, sanitize or allow"},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 7, Message: `folder "/api" must stay inside the workspace`},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 10, Message: `file "../x.md" must stay inside the workspace`},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 11, Message: "reference without a name"},
+		{Severity: "error", Kind: "schema", File: "docs.yaml", Line: 12, Message: "reference entry must be a mapping"},
+	})
+}
+
+func TestCheckWorkspaceBrokenYAML(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"docs.yaml": "name: Broken\ncategories:\n  - name: [API\n",
+	})
+	report := checkWorkspace("broken", root)
+	if len(report.Problems) != 1 || report.Problems[0].Kind != "schema" || report.Problems[0].Line == 0 {
+		t.Errorf("problems = %v, want one schema error with the line yaml reports", report.Problems)
+	}
+}
+
+func TestCheckWorkspaceReferences(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"docs.yaml": `name: Check
+categories:
+  - name: API
+    folder: api
+    references:
+      - name: Missing
+      - name: Button
+      - name: Button
+  - name: Guides
+    folder: guides
+    references:
+      - name: Button
+`,
+		"README.md":        "# Readme\n",
+		"api/Button.md":    "# Button\n\nSee [start](../guides/Start.md).\n\n![shot](img/shot.png) and [gone](Gone.md#usage)\n[out](../../secret.md)\n",
+		"guides/Button.md": "# Button\n",
+		"guides/Start.md":  "# Start\n",
+	})
+	assertProblems(t, checkWorkspace("check", root), []checkProblem{
+		{Severity: "error", Kind: "unresolved", File: "docs.yaml", Line: 6, Message: `no file for "Missing" in category "API" (tried api/Missing.md)`},
+		{Severity: "error", Kind: "duplicate", File: "docs.yaml", Line: 8, Message: `reference "Button" appears more than once in "API"`},
+		{Severity: "error", Kind: "missing-image", File: "api/Button.md", Line: 5, Message: "missing image img/shot.png"},
+		{Severity: "error", Kind: "broken-link", File: "api/Button.md", Line: 5, Message: "broken link Gone.md#usage"},
+		{Severity: "error", Kind: "broken-link", File: "api/Button.md", Line: 6, Message: "broken link ../../secret.md"},
+		{Severity: "warning", Kind: "orphan", File: "guides/Start.md", Message: "not referenced by any category"},
+	})
+}
+
+func TestCheckWorkspaceOutsideLinks(t *testing.T) {
+	parent := t.TempDir()
+	writeFiles(t, parent, map[string]string{
+		"secret.md":       "# Secret\n",
+		"docs/Index.md":   "# Index\n[secret](../secret.md)\n",
+		"docs/README.md":  "# Readme\n",
+		"docs/notes/A.md": "# A\n",
+	})
+	assertProblems(t, checkWorkspace("outside", filepath.Join(parent, "docs")), []checkProblem{
+		{Severity: "error", Kind: "broken-link", File: "Index.md", Line: 2, Message: "../secret.md points outside the workspace"},
+	})
+}
+
+// Discovered trees name references after their files, so the same name in
+// two folders is fine, and so is a README.md below the root
+func TestCheckWorkspaceDiscoveredNames(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":          "# Readme\n",
+		"api/Overview.md":    "# API\n[guides](../guides/Overview.md)\n",
+		"api/README.md":      "# API readme\n",
+		"guides/Overview.md": "# Guides\n",
+	})
+	assertProblems(t, checkWorkspace("discovered", root), []checkProblem{})
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Exit codes, stable for scripts
+const (
+	exitOK      = 0
+	exitFailure = 1 // An error, no results for search or problems found by check
+	exitUsage   = 2
+)
+
+// globalOptions are accepted before the command and after it
+type globalOptions struct {
+	workspace string
+	config    string
+	docsDir   string
+	version   bool
+}
+
+// register adds the global flags to fs, defaulting to the values parsed so far
+func (o *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.workspace, "workspace", o.workspace, "workspace `name` from workspaces.yaml")
+	fs.StringVar(&o.config, "config", o.config, "workspaces.yaml `file` to use")
+	fs.StringVar(&o.docsDir, "docs-dir", o.docsDir, "docs `folder` to open directly, bypassing workspaces.yaml")
+	fs.BoolVar(&o.version, "version", o.version, "print the version and exit")
+}
+
+// resolveWorkspace picks the workspace to open from the flags. Only
+// interactive commands fall back to the workspace selector.
+func (o *globalOptions) resolveWorkspace(interactive bool) (*Workspace, error) {
+	if o.docsDir != "" {
+		dir, err := filepath.Abs(ExpandTilde(o.docsDir))
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+		return &Workspace{Name: filepath.Base(dir), Path: dir}, nil
+	}
+
+	workspaceConfig, err := LoadWorkspaceConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workspace config: %w (create it or pass --docs-dir)", err)
+	}
+	if o.workspace == "" && interactive && len(workspaceConfig.Workspaces) > 1 {
+		if ws := SelectWorkspace(workspaceConfig); ws != nil {
+			return ws, nil
+		}
+		return nil, fmt.Errorf("no workspace selected")
+	}
+	return FindWorkspace(workspaceConfig, o.workspace)
+}
+
+// command is a subcommand of the CLI
+type command struct {
+	name    string
+	summary string
+	run     func(opts *globalOptions, args []string) int
+}
+
+// commands in the order they are listed by --help. tui runs when none is given.
+var commands = []command{
+	{"tui", "Browse the docs interactively (default)", runTUICommand},
+	{"serve", "Serve the web docs without the TUI", runServeCommand},
+	{"export", "Write the web docs as a static site or a single HTML file", runExportCommand},
+	{"search", "Full-text search across document bodies", runSearchCommand},
+	{"list", "List every reference and the file it resolves to", runListCommand},
+	{"check", "Validate docs.yaml, references, files and links", runCheckCommand},
+}
+
+// run parses the command line and returns the process exit code
+func run(args []string) int {
+	opts := &globalOptions{}
+	fs := flag.NewFlagSet(AppName, flag.ContinueOnError)
+	opts.register(fs)
+	fs.Usage = func() { printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	name := "tui"
+	rest := fs.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(opts, rest)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: unknown command %q\n\n", AppName, name)
+	printUsage(fs)
+	return exitUsage
+}
+
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [command flags] [args]\n\nCommands:\n", AppName)
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+// parseCommandFlags parses the flags of a subcommand, global flags included.
+// done is set when the process should exit right away with code.
+func parseCommandFlags(fs *flag.FlagSet, opts *globalOptions, args []string) (code int, done bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, true
+		}
+		return exitUsage, true
+	}
+	if opts.version {
+		fmt.Println(AppName + " " + Version)
+		return exitOK, true
+	}
+	if opts.config != "" {
+		workspacesFile = ExpandTilde(opts.config)
+	}
+	return exitOK, false
+}
+
+// newCommandFlags starts the flag set of a subcommand with the global flags
+func newCommandFlags(name, usage string, opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(AppName+" "+name, flag.ContinueOnError)
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\nFlags:\n", AppName, name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+var errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B"))
+
+// fail reports err on stderr and returns the matching exit code
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, errorStyle.Render("Error: "+err.Error()))
+	return exitFailure
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runTUICommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("tui", "", opts)
+	fs.StringVar(&webAddrFlag, "addr", "", "web preview `address`, host:port or port (default: the workspace's addr, else "+defaultWebAddr+")")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	ws, err := opts.resolveWorkspace(true)
+	if err != nil {
+		return fail(err)
+	}
+	if err := runTUI(ws); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func runServeCommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("serve", "", opts)
+	fs.StringVar(&webAddrFlag, "addr", "", "`address` to listen on, host:port or port (default: the workspace's addr, else "+defaultWebAddr+")")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	ws, err := opts.resolveWorkspace(false)
+	if err != nil {
+		return fail(err)
+	}
+	if err := runServe(ws); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// runSearchCommand prints one tab-separated result per line: category, name,
+// line and snippet. It exits with exitFailure when nothing matches, like grep.
+func runSearchCommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("search", "QUERY...", opts)
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	limit := fs.Int("limit", 0, "maximum `number` of results, 0 for all")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	ws, err := opts.resolveWorkspace(false)
+	if err != nil {
+		return fail(err)
+	}
+	if _, _, err := openWorkspace(ws); err != nil {
+		return fail(err)
+	}
+
+	results := searchResults(query)
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, results); err != nil {
+			return fail(err)
+		}
+	} else {
+		for _, r := range results {
+			fmt.Printf("%s\t%s\t%d\t%s\n", r.Category, r.Name, r.Line, r.Snippet)
+		}
+	}
+
+	if len(results) == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// listEntry is one reference printed by `efx-doc list`
+type listEntry struct {
+	Category    string `json:"category"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	File        string `json:"file,omitempty"` // Relative to the workspace root, empty when missing
+}
+
+// runListCommand prints one tab-separated reference per line: category, name
+// and file, the file left empty when it can't be resolved
+func runListCommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("list", "", opts)
+	asJSON := fs.Bool("json", false, "print references as JSON")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	ws, err := opts.resolveWorkspace(false)
+	if err != nil {
+		return fail(err)
+	}
+	currentWorkspace = ws
+	dataDir := getDataDir()
+	config, err := loadDocsConfig(dataDir)
+	if err != nil {
+		return fail(err)
+	}
+
+	entries := []listEntry{}
+	for _, node := range config.walkCategories() {
+		for _, ref := range node.References {
+			entry := listEntry{Category: node.Key(), Name: ref.Name, Description: ref.Description}
+			if path, err := resolveDoc(config, dataDir, node.Key(), ref.Name); err == nil {
+				if rel, err := filepath.Rel(dataDir, path); err == nil {
+					entry.File = filepath.ToSlash(rel)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, entries); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+	for _, e := range entries {
+		fmt.Printf("%s\t%s\t%s\n", e.Category, e.Name, e.File)
+	}
+	return exitOK
+}
+
+func runExportCommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("export", "", opts)
+	outDir := fs.String("out", "site", "output `folder`")
+	bundle := fs.String("bundle", "", "write a single self-contained HTML `file` instead of a folder")
+	unlisted := fs.Bool("unlisted", false, "also export markdown files no category references")
+	asJSON := fs.Bool("json", false, "print the export report as JSON")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	ws, err := opts.resolveWorkspace(false)
+	if err != nil {
+		return fail(err)
+	}
+	config, warnings, err := openWorkspace(ws)
+	if err != nil {
+		return fail(err)
+	}
+
+	var report exportReport
+	if *bundle != "" {
+		report, err = exportBundle(config, getDataDir(), *bundle, *unlisted)
+	} else {
+		report, err = exportSite(config, getDataDir(), *outDir, *unlisted)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	report.Warnings = append(warnings, report.Warnings...)
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+	for _, w := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning: "+w)
+	}
+	fmt.Printf("Exported %d pages and %d assets to %s\n", report.Pages, report.Assets, report.Out)
+	if report.Removed > 0 {
+		fmt.Printf("Removed %d stale file(s) of the previous export\n", report.Removed)
+	}
+	return exitOK
+}
+
+// runCheckCommand prints one problem per line, "file:line: severity: message",
+// and exits with exitFailure when there are errors, or warnings with --strict
+func runCheckCommand(opts *globalOptions, args []string) int {
+	fs := newCommandFlags("check", "", opts)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	strict := fs.Bool("strict", false, "fail on warnings (orphaned files) too")
+	if code, done := parseCommandFlags(fs, opts, args); done {
+		return code
+	}
+
+	ws, err := opts.resolveWorkspace(false)
+	if err != nil {
+		return fail(err)
+	}
+	currentWorkspace = ws
+	report := checkWorkspace(ws.Name, getDataDir())
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			return fail(err)
+		}
+	} else {
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+		fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	}
+
+	if report.Errors > 0 || (*strict && report.Warnings > 0) {
+		return exitFailure
+	}
+	return exitOK
+}
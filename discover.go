@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// docsConfigName is the manifest looked up at the workspace root
+const docsConfigName = "docs.yaml"
+
+// rootCategoryName holds markdown files sitting directly in the workspace root
+const rootCategoryName = "General"
+
+// frontMatter holds the optional YAML header of a markdown file
+type frontMatter struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+}
+
+// loadDocsConfig loads the navigation tree for a workspace. docs.yaml is used
+// when present; without it the tree is discovered from the directory layout.
+// A docs.yaml with `discover: true` is merged on top of the discovered tree.
+func loadDocsConfig(dataDir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, docsConfigName))
+	if os.IsNotExist(err) {
+		return discoverConfig(dataDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := loadConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Discover {
+		discovered, err := discoverConfig(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		config.merge(discovered, dataDir)
+	}
+	return config, nil
+}
+
+// discoverConfig builds a Config from the directory tree: every subfolder
+// becomes a category, nested subfolders become subcategories, and every .md
+// file a reference.
+func discoverConfig(root string) (*Config, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{Name: filepath.Base(root)}
+
+	rootCat := Category{Name: rootCategoryName, Folder: "."}
+	for _, entry := range entries {
+		if skipDiscovery(entry.Name()) {
+			continue
+		}
+		if entry.IsDir() {
+			if cat, ok := discoverCategory(root, entry.Name()); ok {
+				config.Categories = append(config.Categories, cat)
+			}
+			continue
+		}
+		if isMarkdown(entry.Name()) && !strings.EqualFold(entry.Name(), "README.md") {
+			rootCat.References = append(rootCat.References, discoverReference(root, entry.Name()))
+		}
+	}
+
+	if len(rootCat.References) > 0 {
+		config.Categories = append([]Category{rootCat}, config.Categories...)
+	}
+	return config, nil
+}
+
+// discoverCategory turns root/rel into a category. Folders without any
+// markdown, directly or below, are dropped.
+func discoverCategory(root, rel string) (Category, bool) {
+	cat := Category{Name: titleFromSlug(filepath.Base(rel)), Folder: filepath.Base(rel)}
+
+	entries, err := os.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return cat, false
+	}
+
+	for _, entry := range entries {
+		if skipDiscovery(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(rel, entry.Name())
+		if entry.IsDir() {
+			if sub, ok := discoverCategory(root, path); ok {
+				cat.Categories = append(cat.Categories, sub)
+			}
+		} else if isMarkdown(entry.Name()) {
+			cat.References = append(cat.References, discoverReference(root, path))
+		}
+	}
+	return cat, len(cat.References) > 0 || len(cat.Categories) > 0
+}
+
+// discoverReference describes a single markdown file from its front matter or first paragraph
+func discoverReference(root, rel string) Reference {
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	ref := Reference{Name: name, File: filepath.ToSlash(rel)}
+
+	content, err := os.ReadFile(filepath.Join(root, rel))
+	if err != nil {
+		return ref
+	}
+
+	meta, body := splitFrontMatter(string(content))
+	if meta.Title != "" {
+		ref.Name = meta.Title
+	}
+	ref.Description = meta.Description
+	if ref.Description == "" {
+		ref.Description = firstParagraph(body)
+	}
+	return ref
+}
+
+// splitFrontMatter separates a leading `---` YAML block from the markdown body
+func splitFrontMatter(content string) (frontMatter, string) {
+	var meta frontMatter
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return meta, content
+	}
+
+	rest := content[strings.Index(content, "\n")+1:]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return meta, content
+	}
+
+	yaml.Unmarshal([]byte(rest[:end]), &meta)
+
+	body := rest[end+len("\n---"):]
+	if i := strings.Index(body, "\n"); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	return meta, body
+}
+
+// stripFrontMatter drops the YAML header, which is metadata and never rendered
+func stripFrontMatter(content string) string {
+	_, body := splitFrontMatter(content)
+	return body
+}
+
+// firstParagraph returns the first block of prose, skipping headings and code
+func firstParagraph(body string) string {
+	var lines []string
+	inFence := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if line == "" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<") || strings.HasPrefix(line, "![") {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// titleFromSlug turns a folder name like "getting-started" into "Getting Started"
+func titleFromSlug(slug string) string {
+	words := strings.FieldsFunc(slug, func(r rune) bool {
+		return r == '-' || r == '_' || r == ' '
+	})
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+func isMarkdown(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".md")
+}
+
+// skipDiscovery ignores hidden and underscore-prefixed entries like .git or _drafts
+func skipDiscovery(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// merge adds discovered categories and references that docs.yaml doesn't
+// already declare. Declared entries always win over discovered ones.
+func (c *Config) merge(discovered *Config, dataDir string) {
+	if c.Name == "" {
+		c.Name = discovered.Name
+	}
+
+	// Files already claimed by a declared reference
+	claimed := map[string]bool{}
+	for _, node := range c.walkCategories() {
+		for _, ref := range node.References {
+			if path, err := resolveDoc(c, dataDir, node.Key(), ref.Name); err == nil {
+				claimed[path] = true
+			}
+		}
+	}
+
+	c.Categories = mergeCategories(c.Categories, discovered.Categories, claimed, dataDir)
+}
+
+// mergeCategories merges one level of the discovered tree into the declared one
+func mergeCategories(declared, discovered []Category, claimed map[string]bool, dataDir string) []Category {
+	for _, dcat := range discovered {
+		var cat *Category
+		for i := range declared {
+			if declared[i].Name == dcat.Name ||
+				filepath.Clean(declared[i].FolderName()) == filepath.Clean(dcat.Folder) {
+				cat = &declared[i]
+				break
+			}
+		}
+
+		var refs []Reference
+		for _, ref := range dcat.References {
+			if claimed[filepath.Join(dataDir, ref.File)] {
+				continue
+			}
+			if cat != nil && cat.findReference(ref.Name) != nil {
+				continue
+			}
+			refs = append(refs, ref)
+		}
+
+		if cat == nil {
+			dcat.References = refs
+			dcat.Categories = mergeCategories(nil, dcat.Categories, claimed, dataDir)
+			if len(dcat.References) > 0 || len(dcat.Categories) > 0 {
+				declared = append(declared, dcat)
+			}
+			continue
+		}
+		cat.References = append(cat.References, refs...)
+		cat.Categories = mergeCategories(cat.Categories, dcat.Categories, claimed, dataDir)
+	}
+	return declared
+}
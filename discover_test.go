@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		content string
+		title   string
+		body    string
+	}{
+		{"---\ntitle: Buttons\ndescription: Clickable\n---\n# Button\n", "Buttons", "# Button\n"},
+		{"---\r\ntitle: Buttons\r\n---\r\n# Button\r\n", "Buttons", "# Button\r\n"},
+		{"---\ntitle: Empty\n---", "Empty", ""},
+		{"# Button\n\n---\ntitle: not a header\n---\n", "", "# Button\n\n---\ntitle: not a header\n---\n"},
+		{"---\ntitle: Unclosed\n# Button\n", "", "---\ntitle: Unclosed\n# Button\n"},
+	}
+	for _, tt := range tests {
+		meta, body := splitFrontMatter(tt.content)
+		if meta.Title != tt.title || body != tt.body {
+			t.Errorf("splitFrontMatter(%q) = %q, %q, want %q, %q", tt.content, meta.Title, body, tt.title, tt.body)
+		}
+		if got := stripFrontMatter(tt.content); got != tt.body {
+			t.Errorf("stripFrontMatter(%q) = %q, want %q", tt.content, got, tt.body)
+		}
+	}
+}
+
+// The header names and describes a discovered reference, and is left out
+// of everything that renders the document
+func TestFrontMatterNotRendered(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":     "---\ntitle: Home\nowner: docs-team\n---\n# Readme\n",
+		"api/Button.md": "---\ntitle: Buttons\ndescription: Clickable things\nowner: docs-team\n---\n# Button\nPress it.\n",
+	})
+	openTestWorkspace(t, root)
+
+	config := currentConfig.Load()
+	if len(config.Categories) != 1 || len(config.Categories[0].References) != 1 {
+		t.Fatalf("discovered %+v, want one category with one reference", config.Categories)
+	}
+	ref := config.Categories[0].References[0]
+	if ref.Name != "Buttons" || ref.Description != "Clickable things" {
+		t.Errorf("reference = %q %q, want the front matter's title and description", ref.Name, ref.Description)
+	}
+
+	content, err := loadDocContentFromDisk(config.Categories[0].Name, "Buttons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "# Button\nPress it.\n" {
+		t.Errorf("loadDocContentFromDisk = %q, want the body only", content)
+	}
+
+	mux := newWebMux()
+	for _, url := range []string{"/", "/?cat=" + config.Categories[0].Name + "&doc=Buttons"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		body, _ := io.ReadAll(rec.Body)
+		if strings.Contains(string(body), "docs-team") {
+			t.Errorf("GET %s renders the front matter", url)
+		}
+	}
+
+	outDir := t.TempDir()
+	if _, err := exportSite(config, root, outDir, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"index.html", "api/Button.html"} {
+		html, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(page)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(html), "docs-team") {
+			t.Errorf("exported %s renders the front matter", page)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// docLink is a link of the document shown in the viewport
+type docLink struct {
+	text string
+	dest string
+	line int // 1-based line in the markdown source
+}
+
+var linkMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4")).Bold(true)
+
+// parseDocLinks lists the links of a markdown document in reading order,
+// autolinks included and images left out
+func parseDocLinks(source string) []docLink {
+	src := []byte(source)
+	doc := webMarkdown.Parser().Parse(text.NewReader(src))
+
+	var links []docLink
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			links = append(links, docLink{text: inlineText(n, src), dest: string(n.Destination), line: nodeLine(n, src)})
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink:
+			dest := string(n.URL(src))
+			if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(dest, "mailto:") {
+				dest = "mailto:" + dest
+			}
+			links = append(links, docLink{text: string(n.Label(src)), dest: dest, line: nodeLine(n, src)})
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
+
+// inlineText joins the text segments below n, e.g. the label of a link
+func inlineText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			sb.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				sb.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// linksCurrent reports whether m.links were parsed from the document shown.
+// Reloads and workspace switches replace docContent under the same key.
+func (m *model) linksCurrent() bool {
+	return m.linksKey == m.docCacheKey && m.linksSource == m.docContent
+}
+
+// focusLink moves the link focus by delta, wrapping around, scrolls the
+// viewport to the link and marks its line
+func (m *model) focusLink(delta int) {
+	if !m.linksCurrent() {
+		m.links = parseDocLinks(m.docContent)
+		m.linksKey = m.docCacheKey
+		m.linksSource = m.docContent
+		m.linkIndex = -1
+	}
+	if len(m.links) == 0 {
+		m.toast = "No links in this document"
+		m.toastTimer = 30
+		return
+	}
+
+	if m.linkIndex < 0 && delta < 0 {
+		m.linkIndex = 0
+	}
+	m.linkIndex = (m.linkIndex + delta + len(m.links)) % len(m.links)
+	link := m.links[m.linkIndex]
+
+	// Find the link text in the render the way search hits are found
+	rendered := m.docCache[m.docCacheKey]
+	sourceLines := strings.Count(m.docContent, "\n") + 1
+	term := strings.Fields(link.text)
+	hit := searchHit{line: link.line - 1}
+	if len(term) > 0 {
+		hit.term = term[0]
+	}
+	line := renderedLineOf(rendered, hit, sourceLines)
+
+	lines := strings.Split(rendered, "\n")
+	if line >= 0 && line < len(lines) {
+		lines[line] = linkMarkerStyle.Render("▶") + lines[line]
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height/3)
+	}
+
+	m.toast = fmt.Sprintf("Link %d/%d: %s → %s  [o] open", m.linkIndex+1, len(m.links), link.text, link.dest)
+	m.toastTimer = 60
+}
+
+// followLink opens the focused link. Links to markdown files of the
+// workspace open in the viewport, anything else goes to the system opener.
+func (m *model) followLink() tea.Cmd {
+	if !m.linksCurrent() || m.linkIndex < 0 || m.linkIndex >= len(m.links) {
+		m.toast = "No link selected, use [ and ] to pick one"
+		m.toastTimer = 30
+		return nil
+	}
+	link := m.links[m.linkIndex]
+
+	if !isRelativeLink(link.dest) {
+		if strings.HasPrefix(link.dest, "#") {
+			m.toast = "Links within the page aren't supported"
+			m.toastTimer = 30
+			return nil
+		}
+		return openTarget(link.dest, true)
+	}
+
+	// Relative links start from the folder of the document, the welcome
+	// page sitting at the workspace root
+	dataDir := getDataDir()
+	baseDir := dataDir
+	if m.docPath != "" {
+		baseDir = filepath.Dir(m.docPath)
+	}
+	raw, _ := splitLink(link.dest)
+	rel, err := url.PathUnescape(raw)
+	if err != nil {
+		rel = raw
+	}
+	target := filepath.Join(baseDir, filepath.FromSlash(rel))
+	if _, err := os.Stat(target); err != nil {
+		m.toast = "Broken link: " + link.dest
+		m.toastTimer = 30
+		return nil
+	}
+	if !isMarkdown(target) {
+		return openTarget(target, false)
+	}
+
+	if target == filepath.Join(dataDir, "README.md") {
+		m.loadDoc(welcomeKey)
+		m.viewport.GotoTop()
+		m.toast, m.toastTimer = "", 0
+		return nil
+	}
+	for _, node := range m.config.walkCategories() {
+		for _, ref := range node.References {
+			path, err := resolveDoc(&m.config, dataDir, node.Key(), ref.Name)
+			if err != nil || !sameFile(path, target) {
+				continue
+			}
+			if it := m.selectReference(node.Key(), ref.Name); it != nil && m.serverRunning {
+				updateWebPreview(it.name, it.category, m.docContent)
+			}
+			m.toast, m.toastTimer = "", 0
+			return nil
+		}
+	}
+	m.toast = "Not in the navigation: " + link.dest
+	m.toastTimer = 30
+	return nil
+}
+
+// sameFile compares two paths once cleaned, and by identity when both exist
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// A reload keeps the document key but replaces its source, so the links
+// focused before must not be followed or cycled through afterwards
+func TestFocusLinkAfterReload(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":     "# Readme\n[button](api/Button.md)\n",
+		"api/Button.md": "# Button\n[one](One.md)\n",
+		"api/One.md":    "# One\n",
+		"api/Two.md":    "# Two\n",
+	})
+	openTestWorkspace(t, root)
+	config := currentConfig.Load()
+	m := &model{
+		config:   *config,
+		width:    120,
+		viewport: viewport.New(80, 20),
+		docCache: map[string]string{},
+	}
+
+	m.loadDoc(itemKey(config.Categories[0].Name, "Button"))
+	m.focusLink(1)
+	if len(m.links) != 1 || m.links[m.linkIndex].dest != "One.md" {
+		t.Fatalf("focused %v at %d, want One.md", m.links, m.linkIndex)
+	}
+
+	button := filepath.Join(root, "api", "Button.md")
+	writeFiles(t, root, map[string]string{"api/Button.md": "# Button\n[two](Two.md)\n"})
+	m.reloadWorkspace(workspaceChanges{changed: map[string]bool{button: true}})
+	if m.followLink(); !strings.HasPrefix(m.toast, "No link selected") {
+		t.Errorf("followLink after reload says %q, want the stale focus dropped", m.toast)
+	}
+	m.focusLink(1)
+	if m.links[m.linkIndex].dest != "Two.md" {
+		t.Errorf("focused %v after reload, want Two.md", m.links[m.linkIndex])
+	}
+
+	// Switching workspaces swaps the welcome page under the same key
+	m.loadDoc(welcomeKey)
+	m.focusLink(1)
+	if m.links[m.linkIndex].dest != "api/Button.md" {
+		t.Fatalf("focused %v on the welcome page, want api/Button.md", m.links[m.linkIndex])
+	}
+	m.docContent = "# Other\n[guide](guides/Start.md)\n" // As the W picker sets it
+	m.focusLink(1)
+	if m.links[m.linkIndex].dest != "guides/Start.md" {
+		t.Errorf("focused %v after the welcome page changed, want guides/Start.md", m.links[m.linkIndex])
+	}
+}
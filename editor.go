@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorClosedMsg is sent when the editor started with the e key exits
+type editorClosedMsg struct {
+	path string
+	err  error
+}
+
+// editDoc suspends the TUI and opens file in the user's editor at line
+func editDoc(file string, line int) tea.Cmd {
+	return tea.ExecProcess(editorCommand(file, line), func(err error) tea.Msg {
+		return editorClosedMsg{path: file, err: err}
+	})
+}
+
+// editorCommand opens file at line with $VISUAL or $EDITOR, vi when neither
+// is set. Most editors take +line; the GUI ones that don't are told to wait,
+// so the TUI only comes back once the file is closed.
+func editorCommand(file string, line int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	fields := strings.Fields(editor)
+	args := fields[1:]
+	switch strings.TrimSuffix(filepath.Base(fields[0]), ".exe") {
+	case "code", "code-insiders", "codium":
+		args = append(args, "--wait", "--goto", fmt.Sprintf("%s:%d", file, line))
+	case "subl", "zed":
+		args = append(args, "--wait", fmt.Sprintf("%s:%d", file, line))
+	case "notepad":
+		args = append(args, file)
+	default:
+		args = append(args, fmt.Sprintf("+%d", line), file)
+	}
+	return exec.Command(fields[0], args...)
+}
+
+// docFile returns the markdown file shown for a docCache key, "" when the
+// reference doesn't resolve. The welcome page comes from the root README.md.
+func (m model) docFile(key string) string {
+	if key == welcomeKey {
+		return filepath.Join(getDataDir(), "README.md")
+	}
+	category, name := splitItemKey(key)
+	path, err := resolveDoc(&m.config, getDataDir(), category, name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// topSourceLine maps the line at the top of the viewport back to a line of
+// source, proportionally like renderedLineOf does the other way. Only the
+// body is rendered, so the lines of front matter come on top.
+func (m model) topSourceLine(source string) int {
+	body := stripFrontMatter(source)
+	header := strings.Count(source, "\n") - strings.Count(body, "\n")
+	renderedLines := strings.Count(m.docCache[m.docCacheKey], "\n") + 1
+	sourceLines := strings.Count(body, "\n") + 1
+	return header + m.viewport.YOffset*sourceLines/renderedLines + 1
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sseKeepAlive keeps idle event streams from being closed by proxies
+const sseKeepAlive = 30 * time.Second
+
+// sseEvent is one server-sent event pushed to the browser
+type sseEvent struct {
+	name string
+	data string
+}
+
+// eventHub fans server-sent events out to every open browser tab
+type eventHub struct {
+	mu      sync.Mutex
+	clients map[chan sseEvent]bool
+}
+
+var webEvents = &eventHub{clients: map[chan sseEvent]bool{}}
+
+func (h *eventHub) subscribe() chan sseEvent {
+	ch := make(chan sseEvent, 8)
+	h.mu.Lock()
+	h.clients[ch] = true
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan sseEvent) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// broadcast sends an event to every client without blocking; a tab too
+// slow to drain its queue just misses the event
+func (h *eventHub) broadcast(name, data string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- sseEvent{name: name, data: data}:
+		default:
+		}
+	}
+}
+
+// handleEvents streams hub events to a browser tab as text/event-stream
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ch := webEvents.subscribe()
+	defer webEvents.unsubscribe(ch)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev := <-ch:
+			fmt.Fprintf(w, "event: %s\n", ev.name)
+			for _, line := range strings.Split(ev.data, "\n") {
+				fmt.Fprintf(w, "data: %s\n", line)
+			}
+			fmt.Fprint(w, "\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// teaProgram is the running TUI, nil when only the web server runs
+var teaProgram *tea.Program
+
+// followBrowser enables two-way sync: documents opened in the browser are
+// selected in the TUI as well. Toggled with `b`.
+var followBrowser atomic.Bool
+
+// browserNavigateMsg tells the TUI a document was opened in the browser
+type browserNavigateMsg struct {
+	category string // Key, URL path or name, as accepted by findCategory
+	name     string
+}
+
+// navigateTag marks the URLs of navigate events as ?via=tui, so the page
+// loads they cause aren't taken for browser navigation and sent back to the
+// TUI, which may have moved on by the time they arrive
+const navigateTag = "via"
+
+// tuiURL tags a page URL pushed to the browser by the TUI
+func tuiURL(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	q := u.Query()
+	q.Set(navigateTag, "tui")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// notifyBrowserNavigate forwards a page view to the TUI when two-way sync is on
+func notifyBrowserNavigate(catName, docName string) {
+	if teaProgram == nil || !followBrowser.Load() || docName == "" {
+		return
+	}
+	teaProgram.Send(browserNavigateMsg{category: catName, name: docName})
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+)
+
+// exportReport sums up a static site export
+type exportReport struct {
+	Out      string   `json:"out"`
+	Pages    int      `json:"pages"`
+	Assets   int      `json:"assets"`
+	Removed  int      `json:"removed,omitempty"` // Stale files of the previous export
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// exportManifest lists the files an export wrote to its folder, so the next
+// export there removes pages and assets that are gone without touching
+// anything else the folder holds
+const exportManifest = ".efx-export"
+
+// siteExporter writes the web preview of a workspace as plain HTML files.
+// Pages mirror the layout of the markdown sources (api/Overview.md becomes
+// api/Overview.html), so relative links and images inside documents keep
+// working once .md is swapped for .html.
+type siteExporter struct {
+	config   *Config
+	dataDir  string
+	outDir   string
+	unlisted bool // Export markdown files no category references too
+
+	pages    map[string]string // Sidebar link key to page path, relative to outDir
+	exported map[string]bool   // Markdown files getting a page, relative to dataDir
+	pageDir  string            // Directory of the page being rendered, relative to outDir
+	assets   map[string]bool   // Files referenced by the pages, relative to dataDir
+	written  []string          // Files written, relative to outDir
+	report   exportReport
+
+	// Set for a single-file bundle: pages become <article>s of one document
+	// and assets are inlined as data URIs
+	bundle *strings.Builder
+}
+
+// exportRef is the sidebar entry of an exported file
+type exportRef struct {
+	category string
+	name     string
+}
+
+func newSiteExporter(config *Config, dataDir, out string, unlisted bool) *siteExporter {
+	return &siteExporter{
+		config:   config,
+		dataDir:  dataDir,
+		outDir:   out,
+		unlisted: unlisted,
+		pages:    map[string]string{},
+		exported: map[string]bool{"README.md": true},
+		assets:   map[string]bool{},
+		report:   exportReport{Out: out},
+	}
+}
+
+// exportSite renders every referenced markdown file of the workspace to
+// outDir, with the welcome page as index.html, and copies the images and
+// files they link to. unlisted adds the files no category references.
+func exportSite(config *Config, dataDir, outDir string, unlisted bool) (exportReport, error) {
+	e := newSiteExporter(config, dataDir, outDir, unlisted)
+	if err := e.renderPages(); err != nil {
+		return e.report, err
+	}
+
+	// Copy what the pages link to, in a stable order
+	assets := make([]string, 0, len(e.assets))
+	for rel := range e.assets {
+		assets = append(assets, rel)
+	}
+	sort.Strings(assets)
+	for _, rel := range assets {
+		src := filepath.Join(dataDir, filepath.FromSlash(rel))
+		if info, err := os.Stat(src); err != nil || !info.Mode().IsRegular() {
+			e.warn("missing asset " + rel)
+			continue
+		}
+		if err := copyFile(src, filepath.Join(outDir, filepath.FromSlash(rel))); err != nil {
+			return e.report, err
+		}
+		e.written = append(e.written, rel)
+		e.report.Assets++
+	}
+	return e.report, e.removeStale()
+}
+
+// removeStale deletes the files of the previous export that this one didn't
+// write, then records what it wrote in exportManifest
+func (e *siteExporter) removeStale() error {
+	manifest := filepath.Join(e.outDir, exportManifest)
+	written := map[string]bool{}
+	for _, rel := range e.written {
+		written[rel] = true
+	}
+	if previous, err := os.ReadFile(manifest); err == nil {
+		for _, rel := range strings.Split(string(previous), "\n") {
+			clean := path.Clean(rel)
+			if rel == "" || written[clean] || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+				continue
+			}
+			file := filepath.Join(e.outDir, filepath.FromSlash(clean))
+			if err := os.Remove(file); err == nil {
+				e.report.Removed++
+				// Drop the folders left empty, up to outDir
+				for dir := filepath.Dir(file); dir != filepath.Clean(e.outDir); dir = filepath.Dir(dir) {
+					if os.Remove(dir) != nil {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(e.written)
+	return os.WriteFile(manifest, []byte(strings.Join(e.written, "\n")+"\n"), 0644)
+}
+
+// exportBundle writes the whole workspace as one self-contained HTML file.
+// Every document is an <article> shown by the sidebar links client-side and
+// images are inlined, so the file opens with no server and no network.
+func exportBundle(config *Config, dataDir, file string, unlisted bool) (exportReport, error) {
+	e := newSiteExporter(config, dataDir, file, unlisted)
+	e.bundle = &strings.Builder{}
+	if err := e.renderPages(); err != nil {
+		return e.report, err
+	}
+
+	restore := e.activate()
+	page := generateFullPageHTML(config.Name, bundleStyle+e.bundle.String()+bundleScript, "", "")
+	restore()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return e.report, err
+	}
+	return e.report, os.WriteFile(file, []byte(page), 0644)
+}
+
+// bundleStyle shows one article of a bundle at a time
+const bundleStyle = `<style>
+	.doc { display: none; }
+	.doc.current { display: block; }
+</style>
+`
+
+// bundleScript switches articles on the URL fragment and keeps the sidebar in step
+const bundleScript = `<script>
+	function showDoc() {
+		const id = decodeURIComponent(location.hash.slice(1)) || 'index';
+		const doc = document.getElementById(id) || document.getElementById('index');
+		document.querySelectorAll('.doc.current').forEach(d => d.classList.remove('current'));
+		doc.classList.add('current');
+		document.title = doc.dataset.title;
+		document.querySelectorAll('.cat-items a').forEach(a => {
+			const active = decodeURIComponent(a.getAttribute('href').slice(1)) === doc.id;
+			a.classList.toggle('active', active);
+			for (let cat = active && a.closest('.category'); cat; cat = cat.parentElement.closest('.category')) {
+				cat.classList.add('active');
+			}
+		});
+		document.querySelector('.content').scrollTop = 0;
+	}
+	window.addEventListener('hashchange', showDoc);
+	showDoc();
+</script>
+`
+
+// renderPages renders the welcome page and the markdown files to export
+// through the live preview pipeline, with links made relative. Files no
+// category references are left out unless unlisted is set, so drafts and
+// notes sitting in the docs folder aren't published by accident.
+func (e *siteExporter) renderPages() error {
+	// Sidebar entries point at the page of the file their reference resolves to
+	refsByFile := map[string]exportRef{}
+	for _, node := range e.config.walkCategories() {
+		for _, ref := range node.References {
+			file, err := resolveDoc(e.config, e.dataDir, node.Key(), ref.Name)
+			if err != nil {
+				e.warn(err.Error())
+				continue
+			}
+			rel, _ := filepath.Rel(e.dataDir, file)
+			rel = filepath.ToSlash(rel)
+			e.pages[exportPageKey(node.URLPath(), ref.Name)] = pageFor(rel)
+			refsByFile[rel] = exportRef{category: node.Key(), name: ref.Name}
+			e.exported[rel] = true
+		}
+	}
+
+	files, err := markdownFiles(e.dataDir)
+	if err != nil {
+		return err
+	}
+	if e.unlisted {
+		for _, rel := range files {
+			e.exported[rel] = true
+		}
+	}
+
+	defer e.activate()()
+	md := newWebMarkdown(&linkTransformer{rewrite: e.rewriteLink})
+
+	e.pageDir = "."
+	welcome := generateWelcomeContent(e.config, e.dataDir)
+	if err := e.writePage(md, "index.html", e.config.Name, welcome, "", ""); err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		if rel == "README.md" || !e.exported[rel] {
+			continue // The welcome page, or a file left out
+		}
+		content, err := os.ReadFile(filepath.Join(e.dataDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+
+		title := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+		catName, docName := "", title
+		if ref, ok := refsByFile[rel]; ok {
+			catName, docName, title = ref.category, ref.name, ref.name
+		}
+
+		page := pageFor(rel)
+		e.pageDir = path.Dir(page)
+		if err := e.writePage(md, page, title, stripFrontMatter(string(content)), catName, docName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activate switches page generation to static links until the returned func is called
+func (e *siteExporter) activate() func() {
+	staticSite = true
+	docHref = e.sidebarLink
+	return func() {
+		staticSite = false
+		docHref = docURL
+	}
+}
+
+func (e *siteExporter) warn(msg string) {
+	e.report.Warnings = append(e.report.Warnings, msg)
+}
+
+// writePage renders markdown into a full page at page, relative to outDir
+func (e *siteExporter) writePage(md goldmark.Markdown, page, title, content, catName, docName string) error {
+	body := renderWebHTML(md, content)
+	if e.bundle != nil {
+		fmt.Fprintf(e.bundle, "<article class=\"doc\" id=\"%s\" data-title=\"%s\">\n%s\n%s</article>\n",
+			html.EscapeString(pageID(page)), html.EscapeString(title), generateBreadcrumbHTML(catName, docName), body)
+		e.report.Pages++
+		return nil
+	}
+	fullPage := generateFullPageHTML(title, body, catName, docName)
+
+	target := filepath.Join(e.outDir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, []byte(fullPage), 0644); err != nil {
+		return err
+	}
+	e.written = append(e.written, page)
+	e.report.Pages++
+	return nil
+}
+
+// sidebarLink replaces docURL while exporting
+func (e *siteExporter) sidebarLink(catPath, docName string) string {
+	page := "index.html"
+	if docName != "" {
+		var ok bool
+		if page, ok = e.pages[exportPageKey(catPath, docName)]; !ok {
+			return "#"
+		}
+	}
+	if e.bundle != nil {
+		return "#" + pageID(page)
+	}
+	return relativeURL(e.pageDir, page)
+}
+
+// rewriteLink points links between documents at their pages and records
+// the files linked from the page being rendered so they get copied
+func (e *siteExporter) rewriteLink(dest string, image bool) string {
+	if !isRelativeLink(dest) {
+		return dest
+	}
+	raw, suffix := splitLink(dest)
+	rel, err := url.PathUnescape(raw)
+	if err != nil {
+		return dest
+	}
+	target := path.Clean(path.Join(e.pageDir, rel))
+	if target == ".." || strings.HasPrefix(target, "../") {
+		e.warn("link outside the workspace: " + dest)
+		return dest
+	}
+
+	if !image && isMarkdown(target) {
+		if !e.exported[target] {
+			e.warn("link to a file left out of the export: " + dest)
+			return dest
+		}
+		page := pageFor(target)
+		if target == "README.md" {
+			page = "index.html"
+		}
+		if e.bundle != nil {
+			// Articles have a single anchor, the fragment is dropped
+			return "#" + pageID(page)
+		}
+		return relativeURL(e.pageDir, page) + suffix
+	}
+
+	if e.bundle != nil {
+		if uri, err := dataURI(filepath.Join(e.dataDir, filepath.FromSlash(target))); err == nil {
+			e.report.Assets++
+			return uri
+		}
+		e.warn("missing asset " + target)
+		return dest
+	}
+	e.assets[target] = true
+	return dest
+}
+
+// dataURI inlines a file as a base64 data: URL
+func dataURI(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(file))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// exportPageKey identifies a sidebar entry by the arguments docHref gets
+func exportPageKey(catPath, docName string) string {
+	return catPath + "\x00" + docName
+}
+
+// pageID is the anchor of a page inside a single-file bundle, e.g. "api/Overview"
+func pageID(page string) string {
+	return strings.TrimSuffix(page, ".html")
+}
+
+// pageFor maps a markdown file to its exported page
+func pageFor(rel string) string {
+	return strings.TrimSuffix(rel, path.Ext(rel)) + ".html"
+}
+
+// relativeURL links from a page in fromDir to target, both relative to the site root
+func relativeURL(fromDir, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(fromDir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+}
+
+// markdownFiles lists the markdown files of the workspace as slash paths,
+// skipping the folders auto-discovery skips
+func markdownFiles(dataDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dataDir && skipDiscovery(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMarkdown(d.Name()) {
+			rel, err := filepath.Rel(dataDir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
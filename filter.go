@@ -0,0 +1,270 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// textMatch is where a filter query hit a name, description or category
+type textMatch struct {
+	indexes []int // Byte offsets of the matched runes, for highlighting
+	score   int
+}
+
+// fuzzyMatchMaxSpread rejects matches scattered over long text, e.g. every
+// letter of the query picked from a different word of a description
+const fuzzyMatchMaxSpread = 3
+
+// Scoring of a fuzzy match: every matched rune is worth one point, more when
+// it opens a word or follows the previous match, and every rune skipped
+// between the first and the last match costs one
+const (
+	matchWordStartBonus = 8
+	matchAdjacentBonus  = 4
+)
+
+// typoMinLength is the shortest query typoMatch tolerates a typo in; below
+// it, one edit away matches almost anything
+const typoMinLength = 4
+
+// fuzzyMatch matches pattern against s fzf-style, case-insensitively: the
+// runes of pattern appear in s in order, within a window of at most
+// fuzzyMatchMaxSpread runes per pattern rune. Every window is tried and the
+// best scoring alignment wins, so a substring always matches.
+func fuzzyMatch(pattern, s string) (textMatch, bool) {
+	p := foldRunes(pattern)
+	if len(p) == 0 {
+		return textMatch{}, false
+	}
+	text, offsets := []rune(s), runeOffsets(s)
+	folded := foldRunes(s)
+
+	// A substring is the tightest window there is, and always matches
+	var best textMatch
+	found := false
+	for start := 0; start+len(p) <= len(folded); start++ {
+		if string(folded[start:start+len(p)]) != string(p) {
+			continue
+		}
+		positions := make([]int, len(p))
+		for k := range positions {
+			positions[k] = start + k
+		}
+		if score := scorePositions(text, positions); !found || score > best.score {
+			best, found = textMatch{indexes: byteOffsets(offsets, positions), score: score}, true
+		}
+	}
+	if found {
+		return best, true
+	}
+
+	for start := range folded {
+		if folded[start] != p[0] {
+			continue
+		}
+		end := min(len(folded), start+len(p)*fuzzyMatchMaxSpread)
+		if positions, score, ok := alignWindow(p, text, folded, start, end); ok && (!found || score > best.score) {
+			best, found = textMatch{indexes: byteOffsets(offsets, positions), score: score}, true
+		}
+	}
+	return best, found
+}
+
+// alignWindow finds the best scoring positions of p in folded[start:end],
+// its first rune pinned at start. score[k][j] is the best score of p[:k+1]
+// with p[k] at j, built from the best placement of p[k-1] before j.
+func alignWindow(p, text, folded []rune, start, end int) ([]int, int, bool) {
+	width := end - start
+	const none = -1 << 30
+	score := make([][]int, len(p))
+	from := make([][]int, len(p))
+	for k := range p {
+		score[k] = make([]int, width)
+		from[k] = make([]int, width)
+		for j := range score[k] {
+			score[k][j] = none
+		}
+	}
+	score[0][0] = runeScore(text, start)
+
+	for k := 1; k < len(p); k++ {
+		// Best of score[k-1][i] + i over i < j-1: the gap to j costs j-i-1
+		gapBest, gapFrom := none, -1
+		for j := 1; j < width; j++ {
+			if i := j - 2; i >= 0 && score[k-1][i] != none && score[k-1][i]+i > gapBest {
+				gapBest, gapFrom = score[k-1][i]+i, i
+			}
+			if folded[start+j] != p[k] {
+				continue
+			}
+			if gapFrom >= 0 {
+				score[k][j], from[k][j] = gapBest-(j-1)+runeScore(text, start+j), gapFrom
+			}
+			if prev := score[k-1][j-1]; prev != none && prev+matchAdjacentBonus+runeScore(text, start+j) > score[k][j] {
+				score[k][j], from[k][j] = prev+matchAdjacentBonus+runeScore(text, start+j), j-1
+			}
+		}
+	}
+
+	last, j := len(p)-1, -1
+	for i := range score[last] {
+		if score[last][i] != none && (j < 0 || score[last][i] > score[last][j]) {
+			j = i
+		}
+	}
+	if j < 0 {
+		return nil, 0, false
+	}
+	total := score[last][j]
+	positions := make([]int, len(p))
+	for k := last; k >= 0; k-- {
+		positions[k] = start + j
+		j = from[k][j]
+	}
+	return positions, total, true
+}
+
+// typoMatch matches a query one typo away from the start of a word of s: a
+// rune missing, extra, replaced, or swapped with its neighbour. It is only
+// tried once fuzzyMatch failed, for queries of typoMinLength runes or more.
+func typoMatch(pattern, s string) (textMatch, bool) {
+	p := foldRunes(pattern)
+	if len(p) < typoMinLength {
+		return textMatch{}, false
+	}
+	text, offsets := []rune(s), runeOffsets(s)
+	folded := foldRunes(s)
+
+	for start := range folded {
+		if !isWordStart(text, start) {
+			continue
+		}
+		for _, n := range []int{len(p) + 1, len(p), len(p) - 1} {
+			if start+n > len(folded) || !withinOneEdit(p, folded[start:start+n]) {
+				continue
+			}
+			return textMatch{indexes: offsets[start : start+n], score: -start}, true
+		}
+	}
+	return textMatch{}, false
+}
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted or substituted rune, or two adjacent runes swapped
+func withinOneEdit(a, b []rune) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if i == len(a) {
+		return true
+	}
+	if len(a) < len(b) {
+		return string(a[i:]) == string(b[i+1:])
+	}
+	if string(a[i+1:]) == string(b[i+1:]) {
+		return true
+	}
+	return i+1 < len(a) && a[i] == b[i+1] && a[i+1] == b[i] && string(a[i+2:]) == string(b[i+2:])
+}
+
+// scorePositions scores matched rune positions the way alignWindow does
+func scorePositions(text []rune, positions []int) int {
+	score := 0
+	for k, j := range positions {
+		score += runeScore(text, j)
+		if k > 0 {
+			if gap := j - positions[k-1] - 1; gap == 0 {
+				score += matchAdjacentBonus
+			} else {
+				score -= gap
+			}
+		}
+	}
+	return score
+}
+
+// runeScore is what matching the rune at i is worth, before adjacency
+func runeScore(text []rune, i int) int {
+	if isWordStart(text, i) {
+		return 1 + matchWordStartBonus
+	}
+	return 1
+}
+
+// isWordStart reports whether the rune at i opens a word: it starts the text,
+// follows a separator, or is the upper case hump of a camelCase name
+func isWordStart(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, r := text[i-1], text[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(r)
+}
+
+// foldRunes lower-cases s rune by rune, so indexes line up with []rune(s)
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// runeOffsets maps rune indexes of s to byte offsets
+func runeOffsets(s string) []int {
+	offsets := make([]int, 0, len(s))
+	for i := range s {
+		offsets = append(offsets, i)
+	}
+	return offsets
+}
+
+// byteOffsets maps rune positions to byte offsets, as runeOffsets lists them
+func byteOffsets(offsets, positions []int) []int {
+	indexes := make([]int, len(positions))
+	for k, j := range positions {
+		indexes[k] = offsets[j]
+	}
+	return indexes
+}
+
+// highlightMatches renders s with base, picking out the matched byte offsets.
+// Offsets past a "..." cut by truncate are dropped.
+func highlightMatches(s string, indexes []int, base lipgloss.Style) string {
+	if len(indexes) == 0 {
+		return base.Render(s)
+	}
+
+	limit := len(s)
+	if strings.HasSuffix(s, "...") {
+		limit -= 3
+	}
+	matched := map[int]bool{}
+	for _, idx := range indexes {
+		if idx < limit {
+			matched[idx] = true
+		}
+	}
+
+	var sb strings.Builder
+	for idx, r := range s {
+		if matched[idx] {
+			sb.WriteString(matchStyle.Render(string(r)))
+		} else {
+			sb.WriteString(base.Render(string(r)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       []int // Matched byte offsets, nil when nothing matches
+	}{
+		{"button", "Button", []int{0, 1, 2, 3, 4, 5}},
+		{"BUT", "Button", []int{0, 1, 2}},
+		{"btngrp", "ButtonGroup", []int{0, 2, 5, 6, 7, 10}},
+		// A substring wins over letters picked early on
+		{"install", "Intro to the system: how to install", []int{28, 29, 30, 31, 32, 33, 34}},
+		// The best window, not the first letters found
+		{"gs", "Getting Started", []int{6, 8}},
+		{"gs", "Go to the sidebar", nil},
+		// Word starts rank above the first occurrence
+		{"on", "Button online", []int{7, 8}},
+		{"für", "Menü für Tasten", []int{6, 7, 9}},
+		{"xyz", "Button", nil},
+		{"", "Button", nil},
+	}
+	for _, tt := range tests {
+		match, ok := fuzzyMatch(tt.pattern, tt.s)
+		if ok != (tt.want != nil) || !reflect.DeepEqual(match.indexes, tt.want) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v %v, want %v", tt.pattern, tt.s, match.indexes, ok, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// Earlier in a word and tighter scores higher
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"but", "Button", "Rebuttal"},
+		{"btn", "Button", "Bitterness"},
+		{"gs", "Getting Started", "Bugs"},
+	}
+	for _, tt := range tests {
+		better, _ := fuzzyMatch(tt.pattern, tt.better)
+		worse, _ := fuzzyMatch(tt.pattern, tt.worse)
+		if better.score <= worse.score {
+			t.Errorf("%q scores %d on %q, want above %d on %q", tt.pattern, better.score, tt.better, worse.score, tt.worse)
+		}
+	}
+}
+
+func TestTypoMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       []int
+	}{
+		{"buton", "Button", []int{0, 1, 2, 3, 4, 5}},      // Missing rune
+		{"buttton", "Button", []int{0, 1, 2, 3, 4, 5}},    // Extra rune
+		{"bitton", "Button", []int{0, 1, 2, 3, 4, 5}},     // Replaced rune
+		{"btuton", "Button", []int{0, 1, 2, 3, 4, 5}},     // Swapped runes
+		{"grop", "ButtonGroup", []int{6, 7, 8, 9, 10}},    // A camelCase word
+		{"modla", "Dialog modal", []int{7, 8, 9, 10, 11}}, // Not the first word
+		{"buttn", "Buttons", []int{0, 1, 2, 3, 4, 5}},     // The start of a word
+		{"bton", "Button", nil},                           // Two edits
+		{"btn", "Bat", nil},                               // Too short to guess
+		{"tton", "Button", nil},                           // Not at a word start
+	}
+	for _, tt := range tests {
+		match, ok := typoMatch(tt.pattern, tt.s)
+		if ok != (tt.want != nil) || !reflect.DeepEqual(match.indexes, tt.want) {
+			t.Errorf("typoMatch(%q, %q) = %v %v, want %v", tt.pattern, tt.s, match.indexes, ok, tt.want)
+		}
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+
+	base := lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+	render := func(parts ...string) string {
+		out := ""
+		for i, part := range parts {
+			for _, r := range part {
+				if i%2 == 1 {
+					out += matchStyle.Render(string(r))
+				} else {
+					out += base.Render(string(r))
+				}
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		s       string
+		indexes []int
+		want    string
+	}{
+		{"Button", nil, base.Render("Button")},
+		{"Button", []int{0, 2}, render("", "B", "u", "t", "ton")},
+		{"Menü für", []int{6, 7}, render("Menü ", "fü", "r")},
+		// Offsets cut off by truncate aren't highlighted in the "..."
+		{"Butt...", []int{3, 4, 5}, render("But", "t", "...")},
+	}
+	for _, tt := range tests {
+		if got := highlightMatches(tt.s, tt.indexes, base); got != tt.want {
+			t.Errorf("highlightMatches(%q, %v) = %q, want %q", tt.s, tt.indexes, got, tt.want)
+		}
+	}
+	if matchStyle.Render("B") == base.Render("B") {
+		t.Error("matched and plain runes render the same")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Chroma styles matching the dark and light themes of the web preview
+const (
+	darkCodeStyle  = "github-dark"
+	lightCodeStyle = "github"
+)
+
+// codeFormatter emits CSS classes rather than inline colours, so the theme
+// toggle can restyle code blocks without re-rendering them
+var codeFormatter = chromahtml.New(chromahtml.WithClasses(true))
+
+// codeBlockRenderer highlights fenced code blocks server-side with chroma, so
+// pages come out highlighted with no JavaScript and nothing loaded from a CDN
+type codeBlockRenderer struct{}
+
+func (r *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+
+	var code strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code.Write(segment.Value(source))
+	}
+
+	lexer := lexers.Get(string(n.Language(source)))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err == nil {
+		err = codeFormatter.Format(w, styles.Get(darkCodeStyle), iterator)
+	}
+	if err == nil {
+		w.WriteString("\n")
+	} else {
+		// Plain block, as goldmark would render it
+		w.WriteString("<pre><code>")
+		w.Write(util.EscapeHTML([]byte(code.String())))
+		w.WriteString("</code></pre>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// codeCSS styles highlighted code with dark by default and the light style
+// under body.light. The styles' own backgrounds are left out, code blocks
+// keep the page's pre background.
+func codeCSS(dark *chroma.Style) string {
+	var darkCSS, lightCSS strings.Builder
+	codeFormatter.WriteCSS(&darkCSS, dark)
+	codeFormatter.WriteCSS(&lightCSS, styles.Get(lightCodeStyle))
+
+	var sb strings.Builder
+	for _, css := range []struct {
+		rules, scope string
+	}{{darkCSS.String(), ""}, {lightCSS.String(), "body.light "}} {
+		for _, rule := range strings.Split(css.rules, "\n") {
+			if rule == "" || strings.HasPrefix(rule, "/* Background */") || strings.HasPrefix(rule, "/* PreWrapper */") {
+				continue
+			}
+			sb.WriteString("\t\t" + strings.Replace(rule, "*/ .", "*/ "+css.scope+".", 1) + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// newWebMarkdown creates the goldmark converter used for HTML output, with
+// optional transformers applied to each parsed document. Raw HTML is kept
+// when the open workspace sanitizes or allows it.
+func newWebMarkdown(transformers ...parser.ASTTransformer) goldmark.Markdown {
+	var prioritized []util.PrioritizedValue
+	for _, t := range transformers {
+		prioritized = append(prioritized, util.Prioritized(t, 100))
+	}
+	rendererOptions := []renderer.Option{renderer.WithNodeRenderers(util.Prioritized(&codeBlockRenderer{}, 100))}
+	if rawHTMLMode() != rawHTMLOmit {
+		rendererOptions = append(rendererOptions, html.WithUnsafe())
+	}
+	return goldmark.New(
+		goldmark.WithExtensions(extension.Table),
+		goldmark.WithParserOptions(parser.WithASTTransformers(prioritized...)),
+		goldmark.WithRendererOptions(rendererOptions...),
+	)
+}
+
+// linkTransformer rewrites the destination of every link and image of a document
+type linkTransformer struct {
+	rewrite func(dest string, image bool) string
+}
+
+func (t *linkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = []byte(t.rewrite(string(n.Destination), false))
+		case *ast.Image:
+			n.Destination = []byte(t.rewrite(string(n.Destination), true))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isRelativeLink reports whether dest points at a file relative to the
+// document: no scheme or host, not rooted and not just a #fragment
+func isRelativeLink(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" && u.Host == "" && u.Path != "" && !strings.HasPrefix(u.Path, "/")
+}
+
+// splitLink separates the path of a link from its ?query and #fragment
+func splitLink(dest string) (path, suffix string) {
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		return dest[:i], dest[i:]
+	}
+	return dest, ""
+}
+
+// renderPreviewHTML converts a document for the live web preview. Links to
+// other documents become preview URLs and links to images and other files
+// are served from the workspace by handleFile. Markdown files outside the
+// navigation have neither, and keep their link as written.
+func renderPreviewHTML(content, catName, docName string) string {
+	config, dataDir := currentConfig.Load(), getDataDir()
+	dir := "." // The welcome page sits at the root
+	if file, err := resolveDoc(config, dataDir, catName, docName); err == nil {
+		if rel, err := filepath.Rel(dataDir, filepath.Dir(file)); err == nil {
+			dir = filepath.ToSlash(rel)
+		}
+	}
+
+	var pages map[string]string // Markdown file to preview URL, built on the first document link
+	rewrite := func(dest string, image bool) string {
+		if !isRelativeLink(dest) {
+			return dest
+		}
+		raw, suffix := splitLink(dest)
+		rel, err := url.PathUnescape(raw)
+		if err != nil {
+			return dest
+		}
+		target := path.Clean(path.Join(dir, rel))
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return dest
+		}
+
+		if !image && isMarkdown(target) {
+			if pages == nil {
+				pages = previewPages(config, dataDir)
+			}
+			if page, ok := pages[target]; ok {
+				// The query belongs to the preview URL, only the fragment is kept
+				if i := strings.Index(suffix, "#"); i >= 0 {
+					return page + suffix[i:]
+				}
+				return page
+			}
+			return dest
+		}
+		return fileURL(target) + suffix
+	}
+	return renderWebHTML(newWebMarkdown(&linkTransformer{rewrite: rewrite}), content)
+}
+
+// previewPages maps the markdown files of the navigation, relative to
+// dataDir, to their preview URLs. README.md is the welcome page.
+func previewPages(config *Config, dataDir string) map[string]string {
+	pages := map[string]string{"README.md": docURL("", "")}
+	for _, node := range config.walkCategories() {
+		for _, ref := range node.References {
+			file, err := resolveDoc(config, dataDir, node.Key(), ref.Name)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(dataDir, file); err == nil {
+				pages[filepath.ToSlash(rel)] = docURL(node.URLPath(), ref.Name)
+			}
+		}
+	}
+	return pages
+}
+
+// fileURL is where handleFile serves a workspace file, rel being a slash path
+func fileURL(rel string) string {
+	return "/files/" + (&url.URL{Path: rel}).EscapedPath()
+}
+
+// handleFile serves the images and other files documents link to. Hidden
+// and underscore folders, like the ones discovery skips, are not served,
+// and neither is anything a symlink points at outside of the workspace.
+// Markdown and docs.yaml are refused too: documents are only readable
+// through the navigation, so notes and drafts left unlisted stay private.
+func handleFile(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, "/files/")), "/")
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || skipDiscovery(part) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	if isMarkdown(rel) || strings.EqualFold(path.Base(rel), docsConfigName) {
+		http.NotFound(w, r)
+		return
+	}
+
+	dataDir := getDataDir()
+	file := filepath.Join(dataDir, filepath.FromSlash(rel))
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() || !insideDir(dataDir, file) {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, file)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestWorkspace makes root the current workspace, keeping its search
+// index out of the real config dir
+func openTestWorkspace(t *testing.T, root string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if _, _, err := openWorkspace(&Workspace{Name: "test", Path: root}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		currentWorkspace = nil
+		currentConfig.Store(nil)
+		currentIndex = nil
+	})
+}
+
+func TestHandleDocNotFound(t *testing.T) {
+	root := newTraversalWorkspace(t)
+	openTestWorkspace(t, root)
+	_, symlinked := os.Lstat(filepath.Join(root, "api", "Leak.md"))
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"/?cat=api&doc=Button", http.StatusOK},
+		{"/?cat=API&doc=Button", http.StatusOK},
+		{"/?cat=api&doc=..%2F..%2Fsecret", http.StatusNotFound},
+		{"/?cat=..&doc=..", http.StatusNotFound},
+		{"/?cat=..%2F..&doc=Button", http.StatusNotFound},
+		{"/?cat=api&doc=Escape", http.StatusNotFound},
+		{"/?cat=api&doc=Missing", http.StatusNotFound},
+		{"/secret.md", http.StatusNotFound},
+	}
+	if symlinked == nil {
+		tests = append(tests, struct {
+			url  string
+			want int
+		}{"/?cat=api&doc=Leak", http.StatusNotFound})
+	}
+
+	mux := newWebMux()
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.url, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestHandleFile(t *testing.T) {
+	root := newTraversalWorkspace(t)
+	openTestWorkspace(t, root)
+	_, symlinked := os.Lstat(filepath.Join(root, "api", "Leak.md"))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/files/api/logo.png", http.StatusOK},
+		{"/files/api/Button.md", http.StatusNotFound},
+		{"/files/API/BUTTON.MD", http.StatusNotFound},
+		{"/files/README.md", http.StatusNotFound},
+		{"/files/docs.yaml", http.StatusNotFound},
+		{"/files/api", http.StatusNotFound},
+		{"/files/api/missing.png", http.StatusNotFound},
+		{"/files/.git/config", http.StatusNotFound},
+		{"/files/api/../.git/config", http.StatusNotFound},
+		{"/files/_drafts/Draft.md", http.StatusNotFound},
+		{"/files/../secret.md", http.StatusNotFound},
+		{"/files/api/../../secret.md", http.StatusNotFound},
+		{"/files/../outside/Notes.md", http.StatusNotFound},
+	}
+	if symlinked == nil {
+		tests = append(tests, struct {
+			path string
+			want int
+		}{"/files/api/Leak.md", http.StatusNotFound})
+	}
+
+	for _, tt := range tests {
+		// Straight to the handler: the mux would clean the path with a redirect first
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = tt.path
+		rec := httptest.NewRecorder()
+		handleFile(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestRenderPreviewHTML(t *testing.T) {
+	root := newTraversalWorkspace(t)
+	openTestWorkspace(t, root)
+
+	content := `[usage](Button.md#usage) [home](../README.md) ![logo](logo.png)
+[guide](files/guide%20v2.pdf?v=1) [draft](../_drafts/Draft.md) [secret](../../secret.md)
+[web](https://example.com/a.md) [top](#top)
+`
+	html := renderPreviewHTML(content, "API", "Button")
+	for _, want := range []string{
+		`href="/?cat=api&amp;doc=Button#usage"`, // The query is the preview's, the fragment the link's
+		`href="/"`,                              // README.md is the welcome page
+		`src="/files/api/logo.png"`,
+		`href="/files/api/files/guide%20v2.pdf?v=1"`,
+		`href="../_drafts/Draft.md"`, // Unlisted markdown isn't served
+		`href="../../secret.md"`,     // Outside the workspace
+		`href="https://example.com/a.md"`,
+		`href="#top"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("preview lacks %s:\n%s", want, html)
+		}
+	}
+
+	// On the welcome page, links are relative to the root
+	html = renderPreviewHTML("[button](api/Button.md) ![logo](api/logo.png)", "", "")
+	for _, want := range []string{`href="/?cat=api&amp;doc=Button"`, `src="/files/api/logo.png"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("welcome preview lacks %s:\n%s", want, html)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
+	"sync/atomic"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
@@ -17,15 +21,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/extension"
 	"gopkg.in/yaml.v3"
 )
 
-// Create goldmark with table extension for web preview
-var webMarkdown = goldmark.New(
-	goldmark.WithExtensions(extension.Table),
-)
+// Create goldmark with table extension for web preview, recreated by
+// openWorkspace as the raw HTML setting of docs.yaml applies to it
+var webMarkdown = newWebMarkdown()
 
 // isTerminal checks if we're running in a terminal
 func isTerminal() bool {
@@ -37,8 +38,11 @@ func isTerminal() bool {
 }
 
 // Version info
-const AppName = "efx-doc"
-const Version = "0.1.0"
+// Set at build time by the Makefile with -X
+var (
+	AppName = "efx-doc"
+	Version = "0.1.0"
+)
 
 // Key bindings
 type keyMap struct {
@@ -111,52 +115,75 @@ var keys = keyMap{
 type Config struct {
 	Name        string     `yaml:"name"`
 	Description string     `yaml:"description"`
+	Discover    bool       `yaml:"discover"` // Merge in files found on disk
+	RawHTML     string     `yaml:"raw_html"` // Raw HTML in docs for the web: omit (default), sanitize or allow
 	Categories  []Category `yaml:"categories"`
 }
 
 type Category struct {
 	Name       string      `yaml:"name"`
+	Folder     string      `yaml:"folder"` // Optional, defaults to slugify(Name)
 	References []Reference `yaml:"references"`
+	Categories []Category  `yaml:"categories"` // Nested subcategories
 }
 
 type Reference struct {
 	Name        string `yaml:"name"`
 	Description string `yaml:"description"`
+	File        string `yaml:"file"` // Optional path relative to the docs root
 }
 
 // WorkspaceConfig represents the workspace configuration
 type WorkspaceConfig struct {
+	Opener     string      `yaml:"opener"` // Command opening URLs and folders, e.g. "firefox --new-tab %s"
 	Workspaces []Workspace `yaml:"workspaces"`
 }
 
 type Workspace struct {
 	Name   string `yaml:"name"`
 	Path   string `yaml:"path"`
+	Addr   string `yaml:"addr"` // Web preview address, host:port or port, default 127.0.0.1:8080
 	Styles Styles `yaml:"styles"`
 }
 
 type Styles struct {
-	TUI string `yaml:"tui"`
-	Web string `yaml:"web"`
+	TUI       string `yaml:"tui"`
+	Web       string `yaml:"web"`
+	Templates string `yaml:"templates"` // Folder of .html files overriding the built-in page templates
 }
 
 // Global workspace variables
 var (
 	currentWorkspace *Workspace
-	configDir        string
+	workspacesFile   string // Overrides the default workspaces.yaml, set with --config
 )
 
 // item implements list.Item interface
 type item struct {
 	name        string
 	description string
-	category    string
+	category    string     // Category key, e.g. "API > Components"
+	welcome     bool       // The first item, showing the root README.md and workspace stats
+	hit         *searchHit // Set by the filter when the match is in the document body
+	nameMatches []int      // Byte offsets of fuzzy-matched characters, for highlighting
+	descMatches []int
 }
 
 func (i item) Title() string       { return i.name }
 func (i item) Description() string { return i.description }
 func (i item) FilterValue() string { return i.name + " " + i.description + " " + i.category }
 
+// welcomeKey is the docCache key of the welcome page
+const welcomeKey = "welcome"
+
+// key identifies the document of an item in docCache
+func (i item) key() string {
+	if i.welcome {
+		return welcomeKey
+	}
+	return itemKey(i.category, i.name)
+}
+
 // Global glamour renderer - created once, reused
 var glamourRenderer *glamour.TermRenderer
 var glamourRenderFunc func(string) (string, error)
@@ -164,10 +191,12 @@ var glamourRenderFunc func(string) (string, error)
 // Global HTTP server control
 var (
 	httpServer     *http.Server
-	currentHTML    string
-	currentConfig  *Config // Store config for web navigation
-	currentDocName string  // Current document name
-	currentCatName string  // Current category name
+	webServerURL   string                 // Where httpServer listens, e.g. "http://127.0.0.1:8080"
+	currentHTML    atomic.Pointer[string] // Page served at / without a doc
+	currentConfig  atomic.Pointer[Config] // Config for web navigation, swapped by live reload while handlers read it
+	currentDocName string                 // Current document name
+	currentCatName string                 // Current category name
+	currentIndex   *searchIndex
 )
 
 // Get the config directory path
@@ -191,10 +220,17 @@ func ExpandTilde(path string) string {
 	return path
 }
 
+// WorkspaceConfigPath returns the workspaces.yaml in use
+func WorkspaceConfigPath() string {
+	if workspacesFile != "" {
+		return workspacesFile
+	}
+	return filepath.Join(getConfigDir(), "workspaces.yaml")
+}
+
 // LoadWorkspaceConfig loads the workspace configuration
 func LoadWorkspaceConfig() (*WorkspaceConfig, error) {
-	configPath := filepath.Join(getConfigDir(), "workspaces.yaml")
-	data, err := os.ReadFile(configPath)
+	data, err := os.ReadFile(WorkspaceConfigPath())
 	if err != nil {
 		return nil, err
 	}
@@ -320,6 +356,57 @@ func SelectWorkspace(config *WorkspaceConfig) *Workspace {
 	return RunWorkspaceSelector(config.Workspaces)
 }
 
+// FindWorkspace looks up a workspace by name, case-insensitively. An empty
+// name picks the only workspace when there is just one.
+func FindWorkspace(config *WorkspaceConfig, name string) (*Workspace, error) {
+	if config == nil || len(config.Workspaces) == 0 {
+		return nil, fmt.Errorf("no workspaces configured")
+	}
+	if name == "" {
+		if len(config.Workspaces) == 1 {
+			return &config.Workspaces[0], nil
+		}
+		return nil, fmt.Errorf("several workspaces configured, pick one with --workspace")
+	}
+	for i := range config.Workspaces {
+		if strings.EqualFold(config.Workspaces[i].Name, name) {
+			return &config.Workspaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown workspace %q", name)
+}
+
+// openWorkspace makes ws the current workspace and loads its docs config and
+// search index. warnings explain web styles that failed to load.
+func openWorkspace(ws *Workspace) (config *Config, warnings []string, err error) {
+	currentWorkspace = ws
+	dataDir := getDataDir()
+	config, err = loadDocsConfig(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Store config globally for web navigation
+	currentConfig.Store(config)
+	currentIndex = buildSearchIndex(config, dataDir)
+	webMarkdown = newWebMarkdown()
+
+	// Like the TUI style, a missing or broken web style falls back to the built-in one
+	currentWebTheme = nil
+	if stylePath := GetWebStylePath(); stylePath != "" {
+		if currentWebTheme, err = loadWebTheme(stylePath); err != nil {
+			warnings = append(warnings, "web style ignored: "+err.Error())
+		}
+	}
+	currentPageTemplate = nil
+	if templatesPath := GetTemplatesPath(); templatesPath != "" {
+		if currentPageTemplate, err = loadPageTemplate(templatesPath); err != nil {
+			warnings = append(warnings, "page templates ignored: "+err.Error())
+		}
+	}
+	return config, warnings, nil
+}
+
 // GetWorkspaceDocsPath returns the docs path for the current workspace
 func GetWorkspaceDocsPath() string {
 	if currentWorkspace == nil {
@@ -418,6 +505,10 @@ var (
 	dimStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#666666"))
 
+	matchStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#04B575")).
+			Bold(true)
+
 	helpStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#888888")).
 			MarginTop(1)
@@ -464,20 +555,60 @@ type model struct {
 	viewport      viewport.Model
 	paginator     paginator.Model
 	focusRight    bool              // true = right panel, false = left panel
-	docCache      map[string]string // Cache rendered markdown by doc name
-	docCacheKey   string            // Current cached doc key
+	docCache      map[string]string // Cache rendered markdown by item key
+	docCacheKey   string            // Current cached doc key, welcomeKey or an itemKey
 	docPath       string            // Current doc file path
 	toast         string            // Toast message to display
 	toastTimer    int               // Timer for toast auto-hide
 	serverRunning bool              // Is HTTP server running
+	index         *searchIndex      // Full-text index built at workspace load
+	snapshot      workspaceSnapshot // Last scan of the workspace files, for live reload
+	syncedOffset  int               // Viewport offset last mirrored to the browser
+	links         []docLink         // Links of the document, parsed when focus first moves
+	linksKey      string            // docCacheKey the links were parsed from
+	linksSource   string            // and its docContent, which a reload may change
+	linkIndex     int               // Focused link, -1 for none
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return watchWorkspace(getDataDir())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case workspaceScanMsg:
+		if msg.dataDir != getDataDir() {
+			// Workspace was switched while scanning, start over on the new one
+			return m, watchWorkspace(getDataDir())
+		}
+		changes := diffSnapshots(msg.dataDir, m.snapshot, msg.snapshot)
+		m.snapshot = msg.snapshot
+		if len(changes.changed) > 0 {
+			m.reloadWorkspace(changes)
+		}
+		return m, watchWorkspace(msg.dataDir)
+
+	case browserNavigateMsg:
+		m.followBrowser(msg.category, msg.name)
+		return m, nil
+
+	case editorClosedMsg:
+		// Show the edits now rather than on the next scan of the watcher
+		m.snapshot = snapshotWorkspace(getDataDir())
+		m.reloadWorkspace(workspaceChanges{changed: map[string]bool{msg.path: true}})
+		if msg.err != nil {
+			m.toast = "Editor failed: " + msg.err.Error()
+			m.toastTimer = 30
+		}
+		return m, nil
+
+	case openedMsg:
+		if msg.err != nil {
+			m.toast = "Couldn't open " + msg.target + ": " + msg.err.Error()
+			m.toastTimer = 30
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -522,6 +653,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filtering = false
 				if len(m.filteredItems) > 0 {
 					m.cursor = 0
+					m.openItem(m.filteredItems[m.cursor])
 				}
 				return m, nil
 			case "esc":
@@ -566,23 +698,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(m.filteredItems) > 0 {
 				m.cursor = (m.cursor - 1 + len(m.filteredItems)) % len(m.filteredItems)
 				m.currentPage = m.cursor / m.getItemsPerPage()
-				m.loadDoc(m.filteredItems[m.cursor].name)
-				// Auto-sync to web
-				if m.serverRunning {
-					catName := m.filteredItems[m.cursor].category
-					updateWebPreview(m.filteredItems[m.cursor].name, catName, m.docContent)
-				}
+				m.openItem(m.filteredItems[m.cursor])
 			}
 		case "down", "k", " ":
 			if len(m.filteredItems) > 0 {
 				m.cursor = (m.cursor + 1) % len(m.filteredItems)
 				m.currentPage = m.cursor / m.getItemsPerPage()
-				m.loadDoc(m.filteredItems[m.cursor].name)
-				// Auto-sync to web
-				if m.serverRunning {
-					catName := m.filteredItems[m.cursor].category
-					updateWebPreview(m.filteredItems[m.cursor].name, catName, m.docContent)
-				}
+				m.openItem(m.filteredItems[m.cursor])
 			}
 		case "pgup":
 			m.viewport.PageUp()
@@ -602,7 +724,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor = m.currentPage * m.getItemsPerPage()
 			}
 		case "tab":
-			m.activeTab = (m.activeTab + 1) % (len(m.config.Categories) + 1)
+			// Walk the category tree depth-first, "All" first
+			m.activeTab = (m.activeTab + 1) % (len(m.config.walkCategories()) + 1)
 			m.filter = ""
 			m.filterByTab()
 			m.cursor = 0
@@ -610,7 +733,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "shift+tab":
 			m.activeTab--
 			if m.activeTab < 0 {
-				m.activeTab = len(m.config.Categories)
+				m.activeTab = len(m.config.walkCategories())
 			}
 			m.filter = ""
 			m.filterByTab()
@@ -624,10 +747,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.toastTimer = 30 // Show for 30 ticks
 			}
 		case "f":
-			// Open file location in Finder
+			// Open file location in the file manager
 			if m.docPath != "" {
-				exec.Command("open", filepath.Dir(m.docPath)).Start()
+				return m, openTarget(filepath.Dir(m.docPath), false)
 			}
+		case "]":
+			// Focus the next link of the doc
+			m.focusLink(1)
+			return m, nil
+		case "[":
+			m.focusLink(-1)
+			return m, nil
+		case "o":
+			// Follow the focused link
+			return m, m.followLink()
+		case "e":
+			// Edit the current doc, at the line shown at the top of the viewport
+			path := m.docFile(m.docCacheKey)
+			if path == "" {
+				m.toast = "No file to edit"
+				m.toastTimer = 30
+				return m, nil
+			}
+			source, _ := os.ReadFile(path)
+			return m, editDoc(path, m.topSourceLine(string(source)))
 		case "w":
 			// Open web preview
 			if m.docContent != "" {
@@ -635,26 +778,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Server already running, just open browser
 					m.toast = "Opening..."
 					m.toastTimer = 30
-					go exec.Command("open", "http://localhost:8080").Start()
+					return m, openTarget(webServerURL, true)
 				} else {
 					// Start server
-					m.serverRunning = true
-					m.toast = "Web preview on :8080"
-					m.toastTimer = 30
-					if m.docCacheKey != "welcome" {
-						// Find category for current doc
-						catName := ""
-						for _, item := range m.items {
-							if item.name == m.docCacheKey {
-								catName = item.category
-								break
-							}
-						}
-						go serveMarkdown(m.docCacheKey, m.docContent, catName, m.docCacheKey)
+					var previewURL string
+					var fallback bool
+					var err error
+					if catName, docName := m.currentRef(); docName != "" {
+						previewURL, fallback, err = serveMarkdown(docName, m.docContent, catName, docName)
 					} else {
 						welcomeContent := generateWelcomeContent(&m.config, getDataDir())
-						go serveMarkdown("preview", welcomeContent, "Overview", "README")
+						previewURL, fallback, err = serveMarkdown(m.config.Name, welcomeContent, "", "")
+					}
+					m.toastTimer = 30
+					if err != nil {
+						m.toast = "Web preview failed: " + err.Error()
+						return m, nil
+					}
+					m.serverRunning = true
+					m.toast = "Web preview on " + previewURL
+					if fallback {
+						m.toast = webAddr() + " busy, web preview on " + previewURL
 					}
+					return m, openTarget(previewURL, true)
 				}
 			}
 		case "s":
@@ -666,6 +812,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.toast = "Server stopped"
 				m.toastTimer = 30
 			}
+		case "b":
+			// Toggle two-way sync: the TUI follows documents opened in the browser
+			if followBrowser.Load() {
+				followBrowser.Store(false)
+				m.toast = "Browser sync: TUI → browser"
+			} else {
+				followBrowser.Store(true)
+				m.toast = "Browser sync: two-way"
+			}
+			m.toastTimer = 30
 		case "W":
 			// Switch workspace - stop server first and return to workspace selector
 			if httpServer != nil {
@@ -682,25 +838,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			selected := RunWorkspaceSelector(workspaceConfig.Workspaces)
 			if selected != nil {
-				currentWorkspace = selected
 				// Reload config from new workspace
-				dataDir := getDataDir()
-				configPath := filepath.Join(dataDir, "docs.yaml")
-				configData, err := os.ReadFile(configPath)
+				config, warnings, err := openWorkspace(selected)
 				if err != nil {
 					m.toast = "Failed to load docs config"
 					m.toastTimer = 30
 					return m, nil
 				}
-				config, err := loadConfig(configData)
-				if err != nil {
-					m.toast = "Failed to parse docs config"
-					m.toastTimer = 30
-					return m, nil
-				}
+				dataDir := getDataDir()
 				m.config = *config
 				m.items = createItems(config)
 				m.filteredItems = m.items
+				m.index = currentIndex
+				m.snapshot = snapshotWorkspace(dataDir)
 				m.activeTab = 0
 				m.cursor = 0
 				m.currentPage = 0
@@ -709,9 +859,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				welcomeContent := generateWelcomeContent(config, dataDir)
 				welcomeRendered := RenderMarkdown(welcomeContent, 60)
 				m.viewport.SetContent(welcomeRendered)
+				m.docCache = map[string]string{welcomeKey: welcomeRendered}
+				m.docCacheKey = welcomeKey
+				m.docPath = ""
 				m.docContent = welcomeContent
 				m.toast = "Switched to: " + selected.Name
 				m.toastTimer = 30
+				if len(warnings) > 0 {
+					m.toast += " (" + strings.Join(warnings, "; ") + ")"
+					m.toastTimer = 60
+				}
 			}
 		}
 	}
@@ -735,79 +892,214 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	m.viewport, _ = m.viewport.Update(msg)
+
+	// Mirror the doc scroll position in the browser
+	if m.serverRunning && m.viewport.YOffset != m.syncedOffset {
+		m.syncedOffset = m.viewport.YOffset
+		syncWebScroll(m.viewport.ScrollPercent())
+	}
 	return m, nil
 }
 
-func (m *model) loadDoc(name string) {
-	if cached, ok := m.docCache[name]; ok {
-		m.viewport.SetContent(cached)
-		m.docCacheKey = name
-		return
+// reloadWorkspace applies file changes picked up by the watcher: the
+// manifest and item list are rebuilt when needed, stale renders dropped,
+// the open document re-rendered in place and browsers told to reload
+func (m *model) reloadWorkspace(changes workspaceChanges) {
+	dataDir := getDataDir()
+
+	if changes.manifest {
+		config, err := loadDocsConfig(dataDir)
+		if err != nil {
+			m.toast = "Reload failed: " + err.Error()
+			m.toastTimer = 30
+			return
+		}
+		m.config = *config
+		currentConfig.Store(config)
+		m.items = createItems(config)
+		if m.activeTab > len(m.config.walkCategories()) {
+			m.activeTab = 0
+		}
+		if m.filter != "" {
+			m.applyFilter()
+		} else {
+			m.filterByTab()
+		}
+		if m.cursor >= len(m.filteredItems) {
+			m.cursor = 0
+			m.currentPage = 0
+		}
 	}
 
-	// Handle README specially - use welcome content
-	if name == "README" {
-		welcomeContent := generateWelcomeContent(&m.config, getDataDir())
-		rendered := RenderMarkdown(welcomeContent, m.width*60/100)
-		m.docCache[name] = rendered
-		m.viewport.SetContent(rendered)
-		m.docCacheKey = name
-		m.docPath = filepath.Join(getDataDir(), "docs", "README.md")
-		return
+	if m.index != nil && m.index.update(&m.config, dataDir) {
+		m.index.save()
 	}
 
-	var category string
-	for _, i := range m.items {
-		if i.name == name {
-			category = i.category
-			break
+	// Drop cached renders of the changed files. The welcome page shows stats
+	// and README.md, and unresolved docs may resolve now, so those go on any
+	// manifest change.
+	readme := filepath.Join(dataDir, "README.md")
+	for key := range m.docCache {
+		if key == welcomeKey {
+			if changes.manifest || changes.changed[readme] {
+				delete(m.docCache, key)
+			}
+			continue
+		}
+		path := m.docFile(key)
+		if path == "" || changes.manifest || changes.changed[path] {
+			delete(m.docCache, key)
 		}
 	}
 
-	categoryMap := map[string]string{
-		"Core":       "core",
-		"Responsive": "responsive",
-		"Helpers":    "helpers",
-		"Components": "components",
-		"Templates":  "templates",
-		"Player":     "player",
+	// Re-render the open document, keeping the scroll position
+	if _, ok := m.docCache[m.docCacheKey]; !ok && m.docCacheKey != "" {
+		offset := m.viewport.YOffset
+		if m.docCacheKey == welcomeKey {
+			m.docContent = generateWelcomeContent(&m.config, dataDir)
+			rendered := RenderMarkdown(m.docContent, m.viewport.Width)
+			m.docCache[welcomeKey] = rendered
+			m.viewport.SetContent(rendered)
+		} else {
+			m.loadDoc(m.docCacheKey)
+		}
+		m.viewport.SetYOffset(offset)
+		if m.serverRunning {
+			catName, docName := m.currentRef()
+			updateWebPreview(docName, catName, m.docContent)
+		}
 	}
 
-	folder := categoryMap[category]
-	if folder == "" {
-		folder = "core"
+	webEvents.broadcast("reload", "")
+	m.toast = fmt.Sprintf("Reloaded %d file(s)", len(changes.changed))
+	m.toastTimer = 30
+}
+
+// openItem shows a list item in the viewport, scrolled to its search hit if any
+func (m *model) openItem(it item) {
+	m.loadDoc(it.key())
+	if it.hit != nil && it.hit.line >= 0 {
+		rendered := m.docCache[it.key()]
+		lineCount := m.index.lineCount(it.category, it.name)
+		m.viewport.SetYOffset(renderedLineOf(rendered, *it.hit, lineCount))
 	}
 
-	dataDir := getDataDir()
-	docsDir := filepath.Join(dataDir, folder)
+	// Auto-sync to web
+	if m.serverRunning {
+		catName, docName := m.currentRef()
+		updateWebPreview(docName, catName, m.docContent)
+	}
+}
 
-	possibleNames := []string{
-		name + ".md",
-		strings.ReplaceAll(name, " ", "-") + ".md",
-		strings.ReplaceAll(name, " ", "") + ".md",
+// currentRef is the category and name of the open document, both empty
+// for the welcome page
+func (m model) currentRef() (catName, docName string) {
+	if m.docCacheKey == welcomeKey {
+		return "", ""
 	}
+	return splitItemKey(m.docCacheKey)
+}
 
-	var content []byte
-	var foundPath string
-	for _, fileName := range possibleNames {
-		fullPath := filepath.Join(docsDir, fileName)
-		content, _ = os.ReadFile(fullPath)
-		if content != nil {
-			foundPath = fullPath
+// followBrowser selects a document opened in the browser
+func (m *model) followBrowser(catName, docName string) {
+	if it := m.selectReference(catName, docName); it != nil {
+		// The browser is already showing it, don't bounce a navigate event back
+		setWebPreview(it.name, it.category, m.docContent)
+	}
+}
+
+// selectReference shows a reference as if picked from the list: the tab
+// switches to its category, the cursor moves onto it and the viewport shows
+// it from the top. It returns the selected item, nil when nothing changed.
+func (m *model) selectReference(catName, docName string) *item {
+	node := m.config.findCategory(catName)
+	if node == nil {
+		return nil
+	}
+	ref := node.findReference(docName)
+	if ref == nil {
+		return nil
+	}
+	if m.docCacheKey == itemKey(node.Key(), ref.Name) {
+		return nil
+	}
+
+	for idx, n := range m.config.walkCategories() {
+		if n.Key() == node.Key() {
+			m.activeTab = idx + 1
 			break
 		}
 	}
+	m.filtering = false
+	m.filter = ""
+	m.filterByTab()
+
+	for idx, i := range m.filteredItems {
+		if i.category == node.Key() && i.name == ref.Name {
+			m.cursor = idx
+			m.currentPage = idx / m.getItemsPerPage()
+			m.loadDoc(i.key())
+			m.viewport.GotoTop()
+			return &m.filteredItems[idx]
+		}
+	}
+	return nil
+}
 
-	if content == nil {
-		m.docContent = fmt.Sprintf("# %s\n\nDocumentation not found.\n\nCategory: '%s'\nFolder: '%s'", name, category, folder)
-		m.docCache[name] = m.docContent
+// loadDoc shows a document in the viewport, key being welcomeKey or an itemKey
+func (m *model) loadDoc(key string) {
+	if cached, ok := m.docCache[key]; ok {
+		m.viewport.SetContent(cached)
+		m.docCacheKey = key
+		m.docPath = m.docFile(key)
+		// Only renders are cached, the source is read again for copy, links and the web preview
+		if key == welcomeKey {
+			m.docContent = generateWelcomeContent(&m.config, getDataDir())
+		} else if source, err := os.ReadFile(m.docPath); err == nil && m.docPath != "" {
+			m.docContent = stripFrontMatter(string(source))
+		} else {
+			m.docContent = cached
+		}
+		return
+	}
+
+	// The welcome page shows README.md along with workspace stats
+	if key == welcomeKey {
+		welcomeContent := generateWelcomeContent(&m.config, getDataDir())
+		rendered := RenderMarkdown(welcomeContent, m.width*60/100)
+		m.docCache[key] = rendered
+		m.viewport.SetContent(rendered)
+		m.docCacheKey = key
+		m.docPath = m.docFile(key)
+		m.docContent = welcomeContent
+		return
+	}
+
+	category, name := splitItemKey(key)
+	foundPath, err := resolveDoc(&m.config, getDataDir(), category, name)
+	var content []byte
+	if err == nil {
+		content, err = os.ReadFile(foundPath)
+	}
+
+	if err != nil {
+		m.docContent = fmt.Sprintf("# %s\n\nDocumentation not found.\n\nCategory: '%s'\n\n", name, category)
+		if notFound, ok := err.(*docNotFoundError); ok {
+			m.docContent += "Tried:\n\n"
+			for _, path := range notFound.Tried {
+				m.docContent += "- `" + filepath.ToSlash(path) + "`\n"
+			}
+		} else {
+			m.docContent += err.Error()
+		}
+		m.docCache[key] = m.docContent
 		m.viewport.SetContent(m.docContent)
-		m.docCacheKey = name
+		m.docCacheKey = key
 		m.docPath = ""
 		return
 	}
 
-	m.docContent = string(content)
+	m.docContent = stripFrontMatter(string(content))
 
 	leftWidth := m.width * 40 / 100
 	if leftWidth < 45 {
@@ -820,9 +1112,9 @@ func (m *model) loadDoc(name string) {
 		rendered = m.docContent
 	}
 
-	m.docCache[name] = rendered
+	m.docCache[key] = rendered
 	m.viewport.SetContent(rendered)
-	m.docCacheKey = name
+	m.docCacheKey = key
 	m.docPath = foundPath
 }
 
@@ -832,17 +1124,45 @@ func (m *model) applyFilter() {
 		return
 	}
 
-	filter := strings.ToLower(m.filter)
+	// Global search: always search ALL items. Fuzzy hits on the name rank
+	// first, then names one typo away, then description, then category,
+	// then document bodies.
+	type rankedItem struct {
+		item
+		tier  int
+		score float64
+	}
 
-	// Global search: always search ALL items
-	m.filteredItems = []item{}
+	hits := m.index.hits(m.filter)
+	var ranked []rankedItem
 	for _, i := range m.items {
-		if strings.Contains(strings.ToLower(i.name), filter) ||
-			strings.Contains(strings.ToLower(i.description), filter) ||
-			strings.Contains(strings.ToLower(i.category), filter) {
-			m.filteredItems = append(m.filteredItems, i)
+		if match, ok := fuzzyMatch(m.filter, i.name); ok {
+			i.nameMatches = match.indexes
+			ranked = append(ranked, rankedItem{i, 0, float64(match.score)})
+		} else if match, ok := typoMatch(m.filter, i.name); ok {
+			i.nameMatches = match.indexes
+			ranked = append(ranked, rankedItem{i, 1, float64(match.score)})
+		} else if match, ok := fuzzyMatch(m.filter, i.description); ok {
+			i.descMatches = match.indexes
+			ranked = append(ranked, rankedItem{i, 2, float64(match.score)})
+		} else if match, ok := fuzzyMatch(m.filter, i.category); ok {
+			ranked = append(ranked, rankedItem{i, 3, float64(match.score)})
+		} else if hit, ok := hits[itemKey(i.category, i.name)]; ok {
+			i.hit = &hit
+			ranked = append(ranked, rankedItem{i, 4, hit.score})
 		}
 	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		if ranked[a].tier != ranked[b].tier {
+			return ranked[a].tier < ranked[b].tier
+		}
+		return ranked[a].score > ranked[b].score
+	})
+
+	m.filteredItems = make([]item, len(ranked))
+	for idx, r := range ranked {
+		m.filteredItems[idx] = r.item
+	}
 	m.cursor = 0
 	m.currentPage = 0
 }
@@ -853,17 +1173,74 @@ func (m *model) filterByTab() {
 		return
 	}
 
-	catName := m.config.Categories[m.activeTab-1].Name
+	// A category shows its own references and those of its subcategories
+	node := m.config.walkCategories()[m.activeTab-1]
 	m.filteredItems = []item{}
 	for _, i := range m.items {
-		if i.category == catName {
+		if node.contains(i.category) {
 			m.filteredItems = append(m.filteredItems, i)
 		}
 	}
 }
 
+// activeCategory returns the category selected with Tab, nil for "All"
+func (m model) activeCategory() *categoryNode {
+	nodes := m.config.walkCategories()
+	if m.activeTab <= 0 || m.activeTab > len(nodes) {
+		return nil
+	}
+	return &nodes[m.activeTab-1]
+}
+
+// categoryTreeLines renders the subcategories of the active top-level tab as
+// a tree, expanded along the active path, followed by a breadcrumb
+func (m model) categoryTreeLines() []string {
+	active := m.activeCategory()
+	if active == nil {
+		return nil
+	}
+	top := active.Path[0]
+
+	var lines []string
+	for _, node := range m.config.walkCategories() {
+		if node.Depth() == 0 || node.Path[0] != top {
+			continue
+		}
+		// Only show children of expanded categories (those on the active path)
+		parent := strings.Join(node.Path[:len(node.Path)-1], categoryPathSep)
+		if !keyContains(parent, active.Key()) {
+			continue
+		}
+
+		marker := "  "
+		if len(node.Categories) > 0 {
+			marker = "▸ "
+			if node.contains(active.Key()) {
+				marker = "▾ "
+			}
+		}
+		label := strings.Repeat("  ", node.Depth()) + marker + node.Name
+		if node.Key() == active.Key() {
+			lines = append(lines, selectedStyle.Render(label))
+		} else {
+			lines = append(lines, dimStyle.Render(label))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	breadcrumb := strings.Join(active.Path, " › ")
+	lines = append(lines, "", helpStyle.Copy().MarginTop(0).Render("  "+breadcrumb))
+	return lines
+}
+
 func (m model) getItemsPerPage() int {
-	perPage := m.height - 14
+	perPage := m.height - 14 - len(m.categoryTreeLines())
+	// Search results take two lines: the entry and its snippet
+	if m.filter != "" {
+		perPage /= 2
+	}
 	if perPage < 5 {
 		perPage = 10
 	}
@@ -906,15 +1283,25 @@ func (m model) View() string {
 	left.WriteString(title)
 	left.WriteString("\n\n")
 
-	// Tab bar
+	// Tab bar - top-level categories, subcategories are shown as a tree below
 	var tabsBuilder strings.Builder
 	tabs := []string{"All"}
 	for _, cat := range m.config.Categories {
 		tabs = append(tabs, cat.Name)
 	}
 
+	activeTop := 0
+	if active := m.activeCategory(); active != nil {
+		for i, cat := range m.config.Categories {
+			if cat.Name == active.Path[0] {
+				activeTop = i + 1
+				break
+			}
+		}
+	}
+
 	for i, tab := range tabs {
-		if i == m.activeTab {
+		if i == activeTop {
 			tabsBuilder.WriteString(activeTabStyle.Render(tab))
 		} else {
 			tabsBuilder.WriteString(inactiveTabStyle.Render(tab))
@@ -923,6 +1310,11 @@ func (m model) View() string {
 	left.WriteString(tabsBuilder.String())
 	left.WriteString("\n\n")
 
+	if tree := m.categoryTreeLines(); len(tree) > 0 {
+		left.WriteString(strings.Join(tree, "\n"))
+		left.WriteString("\n\n")
+	}
+
 	// Filter input
 	if m.filtering {
 		left.WriteString("/ " + m.filter + "_")
@@ -983,14 +1375,32 @@ func (m model) View() string {
 			descStyle = dimStyle.Copy().Foreground(lipgloss.Color("#888888"))
 		}
 
-		nameCol := nameStyle.Render(fmt.Sprintf("%s%-*s", prefix, nameWidth, truncate(i.name, nameWidth)))
-		descCol := descStyle.Render(truncate(i.description, descWidth))
+		name := truncate(i.name, nameWidth)
+		padding := ""
+		if len(name) < nameWidth {
+			padding = strings.Repeat(" ", nameWidth-len(name))
+		}
+		nameCol := nameStyle.Render(prefix) + highlightMatches(name, i.nameMatches, nameStyle) + nameStyle.Render(padding)
+		descCol := highlightMatches(truncate(i.description, descWidth), i.descMatches, descStyle)
 		left.WriteString(fmt.Sprintf("%s  %s\n", nameCol, descCol))
 		renderedLines++
+
+		// Matching line from the document body
+		if m.filter != "" {
+			if i.hit != nil {
+				left.WriteString(dimStyle.Render("    " + truncate(i.hit.snippet, availableWidth-2)))
+			}
+			left.WriteString("\n")
+			renderedLines++
+		}
 	}
 
 	// Pad with empty lines
-	for i := renderedLines; i < perPage; i++ {
+	linesPerPage := perPage
+	if m.filter != "" {
+		linesPerPage = perPage * 2
+	}
+	for i := renderedLines; i < linesPerPage; i++ {
 		left.WriteString("\n")
 	}
 
@@ -1040,7 +1450,7 @@ func (m model) View() string {
 	}
 
 	// Help
-	helpText := "[tab] category  [↑↓/k/space]  [←/→/pgup/pgdn] scroll  [enter] copy  [f] folder  [w/s] web  [/?] search  [q] quit"
+	helpText := "[tab] category  [↑↓/k/space]  [←/→/pgup/pgdn] scroll  [enter] copy  [[/]] links  [o] open  [e] edit  [f] folder  [w/s] web  [b] 2-way sync  [/?] search  [q] quit"
 	left.WriteString("\n" + helpStyle.Render(helpText))
 
 	// Left panel rendering - no border
@@ -1161,10 +1571,11 @@ func generateWelcomeContent(config *Config, dataDir string) string {
 		fileCount--
 	}
 
-	// Count total references
+	// Count categories and references across the whole tree
+	nodes := config.walkCategories()
 	refCount := 0
-	for _, cat := range config.Categories {
-		refCount += len(cat.References)
+	for _, node := range nodes {
+		refCount += len(node.References)
 	}
 
 	// Try to load README.md
@@ -1183,11 +1594,11 @@ func generateWelcomeContent(config *Config, dataDir string) string {
 
 	// If README exists, use it as base
 	if len(readmeContent) > 0 {
-		content := string(readmeContent)
+		content := stripFrontMatter(string(readmeContent))
 		// Append stats at the bottom
 		content += "\n\n---\n\n"
 		content += "**Stats**\n\n"
-		content += fmt.Sprintf("- **%d** categories\n", len(config.Categories))
+		content += fmt.Sprintf("- **%d** categories\n", len(nodes))
 		content += fmt.Sprintf("- **%d** references\n", refCount)
 		content += fmt.Sprintf("- **%d** doc files\n", fileCount)
 		return content
@@ -1195,7 +1606,7 @@ func generateWelcomeContent(config *Config, dataDir string) string {
 
 	// Fallback: generate welcome content
 	content := fmt.Sprintf("# %s\n\n%s\n\n---\n\n**Stats**\n\n", projectName, projectDesc)
-	content += fmt.Sprintf("- **%d** categories\n", len(config.Categories))
+	content += fmt.Sprintf("- **%d** categories\n", len(nodes))
 	content += fmt.Sprintf("- **%d** references\n", refCount)
 	content += fmt.Sprintf("- **%d** doc files\n\n", fileCount)
 	content += "---\n\n**Quick Start**\n\n"
@@ -1231,14 +1642,15 @@ func createItems(config *Config) []item {
 		name:        "README",
 		description: "Project overview and stats",
 		category:    "Overview",
+		welcome:     true,
 	})
 
-	for _, cat := range config.Categories {
-		for _, ref := range cat.References {
+	for _, node := range config.walkCategories() {
+		for _, ref := range node.References {
 			items = append(items, item{
 				name:        ref.Name,
 				description: ref.Description,
-				category:    cat.Name,
+				category:    node.Key(),
 			})
 		}
 	}
@@ -1246,40 +1658,17 @@ func createItems(config *Config) []item {
 }
 
 func main() {
-	// Load workspace configuration
-	configDir = getConfigDir()
-	workspaceConfig, err := LoadWorkspaceConfig()
-	if err != nil {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B")).Render("Error: Failed to load workspace config: " + err.Error()))
-		fmt.Println("Please create " + filepath.Join(configDir, "workspaces.yaml"))
-		os.Exit(1)
-	}
-
-	// Select workspace using gum
-	currentWorkspace = SelectWorkspace(workspaceConfig)
-	if currentWorkspace == nil {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B")).Render("Error: No workspace selected"))
-		os.Exit(1)
-	}
-
-	// Read config from external file
-	dataDir := getDataDir()
-	configPath := filepath.Join(dataDir, "docs.yaml")
-
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B")).Render("Error: Failed to read config at " + configPath))
-		os.Exit(1)
-	}
+	os.Exit(run(os.Args[1:]))
+}
 
-	config, err := loadConfig(configData)
+// runTUI opens a workspace in the interactive browser
+func runTUI(ws *Workspace) error {
+	// Read config from docs.yaml, or discover it from the directory tree
+	config, warnings, err := openWorkspace(ws)
 	if err != nil {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B")).Render("Error: " + err.Error()))
-		os.Exit(1)
+		return err
 	}
-
-	// Store config globally for web navigation
-	currentConfig = config
+	dataDir := getDataDir()
 
 	items := createItems(config)
 
@@ -1314,236 +1703,40 @@ func main() {
 		docContent:    welcomeContent,
 		viewport:      vp,
 		paginator:     pager,
-		docCache:      map[string]string{"welcome": welcomeRendered},
-		docCacheKey:   "welcome",
+		docCache:      map[string]string{welcomeKey: welcomeRendered},
+		docCacheKey:   welcomeKey,
+		index:         currentIndex,
+		snapshot:      snapshotWorkspace(dataDir),
+	}
+	if len(warnings) > 0 {
+		m.toast = strings.Join(warnings, "; ")
+		m.toastTimer = 60
 	}
 
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	teaProgram = p
 
-	if err := p.Start(); err != nil {
-		fmt.Println("Error running program:", err)
-		os.Exit(1)
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("running program: %w", err)
 	}
+	return nil
 }
 
-// generateSidebarHTML creates the sidebar navigation HTML
-func generateSidebarHTML(activeCat, activeDoc string) string {
-	if currentConfig == nil {
-		return ""
-	}
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf(`<div class="sidebar"><div class="sidebar-header">efx-motion Docs <span style="font-size:12px;color:#666">%s</span></div>`, Version))
+// docHref builds the sidebar links. It is docURL on the live server and
+// swapped for relative file links while exporting a static site.
+var docHref = docURL
 
-	for _, cat := range currentConfig.Categories {
-		catActive := ""
-		if cat.Name == activeCat {
-			catActive = " active"
-		}
-		sb.WriteString(fmt.Sprintf(`<div class="category%s"><div class="cat-title" onclick="toggleCat(this)">▶ %s</div><div class="cat-items">`, catActive, cat.Name))
-		for _, ref := range cat.References {
-			docActive := ""
-			if ref.Name == activeDoc {
-				docActive = " class=\"active\""
-			}
-			sb.WriteString(fmt.Sprintf(`<a href="/?cat=%s&doc=%s"%s>%s</a>`,
-				urlEncode(cat.Name), urlEncode(ref.Name), docActive, ref.Name))
-		}
-		sb.WriteString(`</div></div>`)
-	}
-	sb.WriteString(`<div class="sidebar-footer">[j/k] navigate • [enter] open • [r] refresh • [q] close</div></div>`)
-	return sb.String()
-}
-
-func urlEncode(s string) string {
-	s = strings.ReplaceAll(s, " ", "-")
-	s = strings.ReplaceAll(s, "&", "%26")
-	s = strings.ReplaceAll(s, "#", "%23")
-	return s
-}
-
-// generateFullPageHTML creates the full page with sidebar
-func generateFullPageHTML(title, content, activeCat, activeDoc string) string {
-	sidebar := generateSidebarHTML(activeCat, activeDoc)
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-	<meta charset="utf-8">
-	<title>%s - efx-motion</title>
-	<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/styles/github-dark.min.css" id="dark-hl">
-	<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/styles/github.min.css" id="light-hl" disabled>
-	<script src="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/highlight.min.js"></script>
-	<script>hljs.highlightAll();</script>
-	<style>
-		* { box-sizing: border-box; margin: 0; padding: 0; }
-		body {
-			font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, sans-serif;
-			background: #0d1117;
-			color: #c9d1d9;
-			display: flex;
-			height: 100vh;
-			overflow: hidden;
-		}
-		body.light {
-			background: #ffffff;
-			color: #24292f;
-		}
-		.theme-toggle {
-			position: fixed;
-			top: 10px;
-			right: 20px;
-			background: #7d56f4;
-			color: white;
-			border: none;
-			padding: 8px 16px;
-			border-radius: 20px;
-			cursor: pointer;
-			font-size: 12px;
-			z-index: 1000;
-		}
-		.theme-toggle:hover { opacity: 0.9; }
-		body.light .theme-toggle { background: #7d56f4; }
-		.sidebar {
-			width: 280px;
-			background: #161b22;
-			border-right: 1px solid #30363d;
-			display: flex;
-			flex-direction: column;
-			overflow: hidden;
-		}
-		body.light .sidebar {
-			background: #f6f8fa;
-			border-right: 1px solid #d0d7de;
-		}
-		.sidebar-header {
-			padding: 16px;
-			font-size: 18px;
-			font-weight: bold;
-			color: #7d56f4;
-			border-bottom: 1px solid #30363d;
-		}
-		body.light .sidebar-header { border-bottom: 1px solid #d0d7de; }
-		.sidebar-footer {
-			padding: 12px;
-			font-size: 11px;
-			color: #8b949e;
-			border-top: 1px solid #30363d;
-			text-align: center;
-		}
-		body.light .sidebar-footer { color: #57606a; border-top-color: #d0d7de; }
-		.category { border-bottom: 1px solid #21262d; }
-		body.light .category { border-bottom: 1px solid #d0d7de; }
-		.cat-title {
-			padding: 10px 16px;
-			cursor: pointer;
-			font-weight: 600;
-			color: #f0f6fc;
-			transition: background 0.2s;
-		}
-		body.light .cat-title { color: #24292f; }
-		.cat-title:hover { background: #21262d; }
-		body.light .cat-title:hover { background: #f3f4f6; }
-		.category.active .cat-title { color: #7d56f4; }
-		.cat-items { display: none; background: #0d1117; }
-		body.light .cat-items { background: #ffffff; }
-		.category.active .cat-items { display: block; }
-		.cat-items a {
-			display: block;
-			padding: 8px 16px 8px 32px;
-			color: #8b949e;
-			text-decoration: none;
-			font-size: 13px;
-			transition: all 0.2s;
-		}
-		body.light .cat-items a { color: #57606a; }
-		.cat-items a:hover { background: #21262d; color: #c9d1d9; }
-		body.light .cat-items a:hover { background: #f3f4f6; color: #24292f; }
-		.cat-items a.active { background: #7d56f420; color: #7d56f4; border-right: 2px solid #7d56f4; }
-		.content {
-			flex: 1;
-			overflow-y: auto;
-			padding: 40px 60px;
-			max-width: calc(100%% - 280px);
-			width: 100%%;
-		}
-		body.light .content { color: #24292f; }
-		pre { background: #161b22; padding: 16px; border-radius: 8px; overflow-x: auto; }
-		body.light pre { background: #f6f8fa; border: 1px solid #d0d7de; }
-		code { font-family: 'Fira Code', 'Monaco', 'Menlo', monospace; font-size: 14px; background: #30363d; color: #e6edf3; padding: 2px 4px; border-radius: 4px; }
-		body.light code { background: #f6f8fa; color: #24292f; }
-		pre code { padding: 0; background: none; }
-		a { color: #58a6ff; text-decoration: none; }
-		a:hover { text-decoration: underline; }
-		h1, h2, h3, h4 { color: #f0f6fc; margin: 24px 0 16px; }
-		body.light h1, body.light h2, body.light h3, body.light h4 { color: #24292f; }
-		h1 { font-size: 2em; border-bottom: 1px solid #30363d; padding-bottom: 10px; }
-		h2 { font-size: 1.5em; border-bottom: 1px solid #30363d; padding-bottom: 8px; }
-		body.light h1, body.light h2 { border-bottom-color: #d0d7de; }
-		blockquote { border-left: 4px solid #7d56f4; margin: 16px 0; padding: 0 16px; color: #8b949e; }
-		body.light blockquote { color: #57606a; }
-		ul, ol { padding-left: 24px; }
-		li { margin: 8px 0; }
-		table { border-collapse: collapse; width: 100%%; margin: 16px 0; }
-		th, td { border: 1px solid #30363d; padding: 10px 14px; text-align: left; }
-		body.light th, body.light td { border-color: #d0d7de; }
-		th { background: #161b22; }
-		body.light th { background: #f6f8fa; }
-		hr { border: none; border-top: 1px solid #30363d; margin: 32px 0; }
-		body.light hr { border-top-color: #d0d7de; }
-	</style>
-</head>
-<body>
-%s
-<button class="theme-toggle" onclick="toggleTheme()">Light</button>
-<div class="content">
-%s
-</div>
-<script>
-	// Theme toggle
-	function toggleTheme() {
-		document.body.classList.toggle('light');
-		var btn = document.querySelector('.theme-toggle');
-		if (document.body.classList.contains('light')) {
-			btn.textContent = 'Dark';
-			document.getElementById('dark-hl').disabled = true;
-			document.getElementById('light-hl').disabled = false;
-		} else {
-			btn.textContent = 'Light';
-			document.getElementById('dark-hl').disabled = false;
-			document.getElementById('light-hl').disabled = true;
-		}
-		localStorage.setItem('theme', document.body.classList.contains('light') ? 'light' : 'dark');
-	}
-	// Load saved theme
-	if (localStorage.getItem('theme') === 'light') {
-		document.body.classList.add('light');
-		document.querySelector('.theme-toggle').textContent = 'Dark';
-		document.getElementById('dark-hl').disabled = true;
-		document.getElementById('light-hl').disabled = false;
-	}
-	function toggleCat(el) { el.parentElement.classList.toggle('active'); }
-	let currentIdx = 0;
-	const links = document.querySelectorAll('.cat-items a');
-	links.forEach((link, idx) => { if (link.classList.contains('active')) currentIdx = idx; });
-	document.addEventListener('keydown', (e) => {
-		if (e.key === 'j' || e.key === 'ArrowDown') {
-			currentIdx = Math.min(currentIdx + 1, links.length - 1);
-			links[currentIdx].click();
-		} else if (e.key === 'k' || e.key === 'ArrowUp') {
-			currentIdx = Math.max(currentIdx - 1, 0);
-			links[currentIdx].click();
-		} else if (e.key === 'Enter' || e.key === 'r') {
-			location.reload();
-		} else if (e.key === 'q') {
-			window.close();
-		}
-	});
-</script>
-</body>
-</html>`, title, sidebar, content)
+// staticSite is set while exporting: pages are plain files, without the
+// search endpoint or the event stream of the live server
+var staticSite bool
 
-	return html
+// docURL links to a reference in the web preview, catPath being a
+// categoryNode URLPath. Without a name it is the welcome page at the root.
+func docURL(catPath, docName string) string {
+	if docName == "" {
+		return "/"
+	}
+	return "/?" + url.Values{"cat": {catPath}, "doc": {docName}}.Encode()
 }
 
 // updateWebPreview updates the web preview with new content
@@ -1551,133 +1744,172 @@ func updateWebPreview(docName, catName, content string) {
 	if httpServer == nil {
 		return
 	}
+	setWebPreview(docName, catName, content)
+
+	// Make open tabs follow the TUI
+	catPath := catName
+	if node := currentConfig.Load().findCategory(catName); node != nil {
+		catPath = node.URLPath()
+	}
+	webEvents.broadcast("navigate", tuiURL(docURL(catPath, docName)))
+}
+
+// setWebPreview replaces the page served at / without notifying open tabs
+func setWebPreview(docName, catName, content string) {
+	if httpServer == nil {
+		return
+	}
 
 	currentDocName = docName
 	currentCatName = catName
 
-	// Convert markdown to HTML
-	var buf strings.Builder
-	if err := webMarkdown.Convert([]byte(content), &buf); err != nil {
-		buf.WriteString(content)
-	}
-	htmlContent := buf.String()
+	page := generateFullPageHTML(docName, renderPreviewHTML(content, catName, docName), catName, docName)
+	currentHTML.Store(&page)
+}
 
-	// Generate full page
-	currentHTML = generateFullPageHTML(docName, htmlContent, catName, docName)
+// syncWebScroll mirrors the TUI viewport position in open tabs
+func syncWebScroll(percent float64) {
+	if httpServer == nil {
+		return
+	}
+	webEvents.broadcast("scroll", strconv.FormatFloat(percent, 'f', 4, 64))
 }
 
-// serveMarkdown starts the HTTP server with full page navigation
-func serveMarkdown(title, content, catName, docName string) {
+// serveMarkdown starts the HTTP server with full page navigation and
+// returns its URL. fallback is set when the configured port was busy.
+func serveMarkdown(title, content, catName, docName string) (url string, fallback bool, err error) {
 	currentDocName = docName
 	currentCatName = catName
 
-	// Convert markdown to HTML
-	var buf strings.Builder
-	if err := webMarkdown.Convert([]byte(content), &buf); err != nil {
-		buf.WriteString(content)
-	}
-	htmlContent := buf.String()
-
-	// Generate full page
-	currentHTML = generateFullPageHTML(title, htmlContent, catName, docName)
+	page := generateFullPageHTML(title, renderPreviewHTML(content, catName, docName), catName, docName)
+	currentHTML.Store(&page)
 
 	// If server already running, just return (content updated)
 	if httpServer != nil {
-		return
+		return webServerURL, false, nil
+	}
+
+	// Listen first, so a busy port or a bad address is reported rather than lost
+	listener, fallback, err := listenWeb(webAddr())
+	if err != nil {
+		return "", false, err
 	}
+	httpServer = &http.Server{Handler: newWebMux()}
+	webServerURL = webURL(listener.Addr())
+
+	go httpServer.Serve(listener)
+	return webServerURL, fallback, nil
+}
 
-	// Start server
+// newWebMux routes the web preview, shared by the TUI and `efx-doc serve`
+func newWebMux() *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-
-		// Parse URL params
-		params := r.URL.Query()
-		docName := params.Get("doc")
-		catName := params.Get("cat")
-
-		var content string
-		var html string
-
-		if docName != "" {
-			// Load document from disk
-			content = loadDocContentFromDisk(catName, docName)
-			if content != "" {
-				// Convert markdown to HTML
-				var buf strings.Builder
-				if err := webMarkdown.Convert([]byte(content), &buf); err != nil {
-					buf.WriteString(content)
-				}
-				html = generateFullPageHTML(docName, buf.String(), catName, docName)
-			}
-		}
+	mux.HandleFunc("/", handleDoc)
+	mux.HandleFunc("/search", handleSearch)
+	mux.HandleFunc("/files/", handleFile)
+	mux.HandleFunc("/events", handleEvents)
+	return mux
+}
+
+// handleDoc renders the document named by ?cat=&doc=, or the current page.
+// Only references declared in the workspace are served, anything else is a 404.
+func handleDoc(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+
+	if r.URL.Path != "/" {
+		writeNotFound(w, r.URL.Path+" does not exist")
+		return
+	}
+
+	// Parse URL params
+	params := r.URL.Query()
+	docName := params.Get("doc")
+	catName := params.Get("cat")
 
-		// If no valid doc, use current content
-		if html == "" {
-			html = currentHTML
+	// Without a document, show the page the TUI last selected
+	if docName == "" {
+		if page := currentHTML.Load(); page != nil {
+			fmt.Fprint(w, *page)
 		}
+		return
+	}
 
-		fmt.Fprint(w, html)
-	})
+	content, err := loadDocContentFromDisk(catName, docName)
+	if err != nil {
+		writeNotFound(w, err.Error())
+		return
+	}
+	if params.Get(navigateTag) == "" {
+		notifyBrowserNavigate(catName, docName)
+	}
+	fmt.Fprint(w, generateFullPageHTML(docName, renderPreviewHTML(content, catName, docName), catName, docName))
+}
 
-	httpServer = &http.Server{Addr: ":8080", Handler: mux}
+// writeNotFound answers with a 404 page keeping the sidebar, so readers can move on
+func writeNotFound(w http.ResponseWriter, reason string) {
+	w.WriteHeader(http.StatusNotFound)
+	content := "<h1>Not found</h1>\n<p>" + html.EscapeString(reason) + "</p>\n"
+	fmt.Fprint(w, generateFullPageHTML("Not found", content, "", ""))
+}
 
-	// Open browser
-	go func() {
-		time.Sleep(300 * time.Millisecond)
-		exec.Command("open", "http://localhost:8080").Start()
-	}()
+// webSearchResult is one entry returned by the /search endpoint
+type webSearchResult struct {
+	Category string  `json:"category"`
+	Name     string  `json:"name"`
+	Snippet  string  `json:"snippet,omitempty"`
+	Line     int     `json:"line"` // 1-based line of the snippet
+	Score    float64 `json:"score"`
+	URL      string  `json:"url"`
+}
 
-	go func() {
-		httpServer.ListenAndServe()
-	}()
+// handleSearch serves ranked full-text results as JSON, used by the sidebar search box
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(searchResults(r.URL.Query().Get("q")))
 }
 
-// loadDocContentFromDisk loads markdown content from disk based on category and doc name
-func loadDocContentFromDisk(catName, docName string) string {
-	if catName == "" || docName == "" {
-		return ""
-	}
+// searchResults ranks the references of the current workspace against query
+func searchResults(query string) []webSearchResult {
+	hits := currentIndex.hits(query)
 
-	// Map category names to folder names
-	categoryMap := map[string]string{
-		"Core":       "core",
-		"Responsive": "responsive",
-		"Helpers":    "helpers",
-		"Components": "components",
-		"Templates":  "templates",
-		"Player":     "player",
+	results := []webSearchResult{}
+	for _, node := range currentConfig.Load().walkCategories() {
+		for _, ref := range node.References {
+			hit, ok := hits[itemKey(node.Key(), ref.Name)]
+			if !ok {
+				continue
+			}
+			results = append(results, webSearchResult{
+				Category: node.Key(),
+				Name:     ref.Name,
+				Snippet:  hit.snippet,
+				Line:     hit.line + 1,
+				Score:    hit.score,
+				URL:      docURL(node.URLPath(), ref.Name),
+			})
+		}
 	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
 
-	folder := categoryMap[catName]
-	if folder == "" {
-		folder = "core"
+// loadDocContentFromDisk loads markdown content from disk based on category and doc name
+func loadDocContentFromDisk(catName, docName string) (string, error) {
+	docPath, err := resolveDoc(currentConfig.Load(), getDataDir(), catName, docName)
+	if err != nil {
+		return "", err
 	}
 
-	dataDir := getDataDir()
-	docsDir := filepath.Join(dataDir, folder)
-
-	// Try different file name variations
-	possibleNames := []string{
-		docName + ".md",
-		strings.ReplaceAll(docName, " ", "-") + ".md",
-		strings.ReplaceAll(docName, " ", "") + ".md",
-		strings.ReplaceAll(docName, "-", " ") + ".md",
-		strings.ReplaceAll(docName, "-", "") + ".md",
-	}
-
-	for _, fileName := range possibleNames {
-		fullPath := filepath.Join(docsDir, fileName)
-		content, err := os.ReadFile(fullPath)
-		if err == nil && len(content) > 0 {
-			return string(content)
-		}
+	content, err := os.ReadFile(docPath)
+	if err != nil {
+		return "", err
 	}
-
-	return ""
+	return stripFrontMatter(string(content)), nil
 }
 
 // Keep list import used
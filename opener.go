@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openedMsg reports how opening a URL or a folder went
+type openedMsg struct {
+	target string
+	err    error
+}
+
+// openTarget opens target with the system handler, a URL in the browser or
+// a folder in the file manager, and reports failures as an openedMsg
+func openTarget(target string, isURL bool) tea.Cmd {
+	return func() tea.Msg {
+		cmd, err := openerCommand(target, isURL)
+		if err == nil {
+			err = cmd.Run()
+		}
+		return openedMsg{target: target, err: err}
+	}
+}
+
+// openerCommand picks the command opening target: the opener of
+// workspaces.yaml, then $BROWSER for URLs, then the platform's default.
+// In a configured command %s stands for the target, which is appended otherwise.
+func openerCommand(target string, isURL bool) (*exec.Cmd, error) {
+	if config, err := LoadWorkspaceConfig(); err == nil && config.Opener != "" {
+		return commandWithTarget(config.Opener, target)
+	}
+
+	// $BROWSER is a list of commands, the first one found is used
+	if browsers := os.Getenv("BROWSER"); isURL && browsers != "" {
+		for _, browser := range filepath.SplitList(browsers) {
+			if cmd, err := commandWithTarget(browser, target); err == nil && cmd.Err == nil {
+				return cmd, nil
+			}
+		}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", target), nil
+	case "windows":
+		// start is a cmd builtin, its first quoted argument is a window title
+		return exec.Command("cmd", "/c", "start", "", strings.ReplaceAll(target, "&", "^&")), nil
+	default:
+		return exec.Command("xdg-open", target), nil
+	}
+}
+
+// commandWithTarget splits a command line on spaces and puts target in place of %s
+func commandWithTarget(command, target string) (*exec.Cmd, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty opener command")
+	}
+	replaced := false
+	for i, field := range fields {
+		if strings.Contains(field, "%s") {
+			fields[i] = strings.ReplaceAll(field, "%s", target)
+			replaced = true
+		}
+	}
+	if !replaced {
+		fields = append(fields, target)
+	}
+	return exec.Command(fields[0], fields[1:]...), nil
+}
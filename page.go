@@ -0,0 +1,207 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// The page templates, one per file, named after it: layout renders the
+// page and includes the style, sidebar, header, footer and script partials
+//
+//go:embed web/*.html
+var webTemplates embed.FS
+
+// basePageTemplate holds the built-in templates, parsed once
+var basePageTemplate = template.Must(newPageTemplate())
+
+// newPageTemplate parses the built-in templates. An executed template can't
+// be cloned, so each workspace override starts from a fresh set.
+func newPageTemplate() (*template.Template, error) {
+	web, err := fs.Sub(webTemplates, "web")
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplateDir(template.New("page"), web)
+}
+
+// currentPageTemplate is the page template of the open workspace, nil for the built-in one
+var currentPageTemplate *template.Template
+
+// pageData is what the templates render
+type pageData struct {
+	Title       string // Document title, empty on the welcome page
+	SiteName    string // docs.yaml name, or the workspace name
+	Description string
+	AppName     string
+	Version     string
+	HomeURL     string
+	Breadcrumbs []string // Category path and document name
+	Nav         []navCategory
+	Content     template.HTML // The rendered markdown
+	ThemeCSS    template.CSS  // Code highlighting and styles.web palette
+	Static      bool          // Exported page, without search or live reload
+}
+
+// navCategory is a category of the sidebar, with its subcategories
+type navCategory struct {
+	Name       string
+	Active     bool // On the path of the current document, shown expanded
+	Docs       []navDoc
+	Categories []navCategory
+}
+
+type navDoc struct {
+	Name   string
+	URL    string
+	Active bool
+}
+
+// parseTemplateDir adds every .html file of fsys to t as a template named
+// after the file, replacing a template of the same name
+func parseTemplateDir(t *template.Template, fsys fs.FS) (*template.Template, error) {
+	files, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		text, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.New(strings.TrimSuffix(file, ".html")).Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return t, nil
+}
+
+// GetTemplatesPath returns the folder of template overrides, empty when the workspace has none
+func GetTemplatesPath() string {
+	if currentWorkspace == nil || currentWorkspace.Styles.Templates == "" {
+		return ""
+	}
+	return ExpandTilde(currentWorkspace.Styles.Templates)
+}
+
+// loadPageTemplate layers the templates found in dir over the built-in
+// ones, so a workspace can override a single partial like footer.html
+func loadPageTemplate(dir string) (*template.Template, error) {
+	// os.DirFS of a missing folder globs nothing, which would hide a typo
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", dir)
+	}
+	t, err := newPageTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplateDir(t, os.DirFS(dir))
+}
+
+// newPageData gathers the data of a page. activeCat may be a key, a URL
+// path or a plain name.
+func newPageData(title, content, activeCat, activeDoc string) pageData {
+	data := pageData{
+		Title:    title,
+		SiteName: AppName,
+		AppName:  AppName,
+		Version:  Version,
+		HomeURL:  docHref("", ""),
+		Content:  template.HTML(content),
+		ThemeCSS: template.CSS(currentWebTheme.CSS()),
+		Static:   staticSite,
+	}
+	if currentWorkspace != nil && currentWorkspace.Name != "" {
+		data.SiteName = currentWorkspace.Name
+	}
+	config := currentConfig.Load()
+	if config == nil {
+		return data
+	}
+	if config.Name != "" {
+		data.SiteName = config.Name
+	}
+	if data.Title == data.SiteName {
+		data.Title = "" // The welcome page
+	}
+	data.Description = config.Description
+
+	activeKey := ""
+	if node := config.findCategory(activeCat); node != nil {
+		activeKey = node.Key()
+		data.Breadcrumbs = breadcrumbs(node, activeDoc)
+	}
+	data.Nav = newNav(config.walkCategories(), activeKey, activeDoc)
+	return data
+}
+
+// newNav nests the depth-first list of categories back into a tree
+func newNav(nodes []categoryNode, activeKey, activeDoc string) []navCategory {
+	var nav []navCategory
+	for i := 0; i < len(nodes); {
+		node := nodes[i]
+		end := i + 1
+		for end < len(nodes) && nodes[end].Depth() > node.Depth() {
+			end++
+		}
+
+		cat := navCategory{Name: node.Name, Active: node.contains(activeKey)}
+		for _, ref := range node.References {
+			cat.Docs = append(cat.Docs, navDoc{
+				Name:   ref.Name,
+				URL:    docHref(node.URLPath(), ref.Name),
+				Active: node.Key() == activeKey && slugify(ref.Name) == slugify(activeDoc),
+			})
+		}
+		cat.Categories = newNav(nodes[i+1:end], activeKey, activeDoc)
+		nav = append(nav, cat)
+		i = end
+	}
+	return nav
+}
+
+// breadcrumbs shows where a document sits in the category tree
+func breadcrumbs(node *categoryNode, docName string) []string {
+	if ref := node.findReference(docName); ref != nil {
+		docName = ref.Name
+	}
+	return append(append([]string{}, node.Path...), docName)
+}
+
+// pageTemplate returns the workspace templates, or the built-in ones
+func pageTemplate() *template.Template {
+	if currentPageTemplate != nil {
+		return currentPageTemplate
+	}
+	return basePageTemplate
+}
+
+// generateBreadcrumbHTML renders the breadcrumb partial on its own
+func generateBreadcrumbHTML(activeCat, activeDoc string) string {
+	node := currentConfig.Load().findCategory(activeCat)
+	if node == nil {
+		return ""
+	}
+	var sb strings.Builder
+	if err := pageTemplate().ExecuteTemplate(&sb, "breadcrumb", breadcrumbs(node, activeDoc)); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// generateFullPageHTML creates the full page with sidebar
+func generateFullPageHTML(title, content, activeCat, activeDoc string) string {
+	var sb strings.Builder
+	data := newPageData(title, content, activeCat, activeDoc)
+	if err := pageTemplate().ExecuteTemplate(&sb, "layout", data); err != nil {
+		// A broken override shouldn't leave the browser with half a page
+		return "<!DOCTYPE html><title>Template error</title><pre>" + html.EscapeString(err.Error()) + "</pre>"
+	}
+	return sb.String()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// slugify derives a folder name from a display name: lowercase, spaces to dashes.
+// "Getting Started" becomes "getting-started".
+func slugify(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "-")
+}
+
+// categoryPathSep joins category names into a breadcrumb like "API > Components > Forms"
+const categoryPathSep = " > "
+
+// FolderName returns the folder holding the category's markdown files,
+// relative to its parent category (or the workspace root at the top level).
+// An explicit `folder:` in docs.yaml wins, otherwise the folder is derived
+// from the category name with slugify.
+func (c Category) FolderName() string {
+	if c.Folder != "" {
+		return c.Folder
+	}
+	return slugify(c.Name)
+}
+
+// categoryNode is a category together with its position in the tree
+type categoryNode struct {
+	*Category
+	Path []string // Display names from the top level down
+	Dir  string   // Folder relative to the workspace root
+}
+
+// Key identifies the category across the TUI and the web preview
+func (n categoryNode) Key() string {
+	return strings.Join(n.Path, categoryPathSep)
+}
+
+// Depth is 0 for top-level categories
+func (n categoryNode) Depth() int {
+	return len(n.Path) - 1
+}
+
+// URLPath is the slug form of Key used in web preview links, e.g. "api/components/forms"
+func (n categoryNode) URLPath() string {
+	slugs := make([]string, len(n.Path))
+	for i, name := range n.Path {
+		slugs[i] = slugify(name)
+	}
+	return strings.Join(slugs, "/")
+}
+
+// contains reports whether key is this category or one of its descendants
+func (n categoryNode) contains(key string) bool {
+	return keyContains(n.Key(), key)
+}
+
+// keyContains reports whether key equals parent or sits below it in the tree
+func keyContains(parent, key string) bool {
+	return key == parent || strings.HasPrefix(key, parent+categoryPathSep)
+}
+
+// walkCategories lists every category depth-first, parents before children
+func (c *Config) walkCategories() []categoryNode {
+	if c == nil {
+		return nil
+	}
+	var nodes []categoryNode
+	var walk func(cats []Category, path []string, folder string)
+	walk = func(cats []Category, path []string, folder string) {
+		for i := range cats {
+			cat := &cats[i]
+			node := categoryNode{
+				Category: cat,
+				Path:     append(append([]string{}, path...), cat.Name),
+				Dir:      filepath.Join(folder, cat.FolderName()),
+			}
+			nodes = append(nodes, node)
+			walk(cat.Categories, node.Path, node.Dir)
+		}
+	}
+	walk(c.Categories, nil, "")
+	return nodes
+}
+
+// findCategory looks up a category by its key ("API > Components"), its URL
+// path ("api/components") or, failing that, by its display name anywhere in
+// the tree. Slug forms are accepted as well, as found in older web
+// preview links.
+func (c *Config) findCategory(name string) *categoryNode {
+	if c == nil || name == "" {
+		return nil
+	}
+	nodes := c.walkCategories()
+
+	for i := range nodes {
+		if nodes[i].Key() == name {
+			return &nodes[i]
+		}
+	}
+
+	slugPath := name
+	if strings.Contains(name, categoryPathSep) {
+		slugPath = strings.ReplaceAll(name, categoryPathSep, "/")
+	}
+	segments := strings.Split(slugPath, "/")
+	for i := range segments {
+		segments[i] = slugify(segments[i])
+	}
+	slugPath = strings.Join(segments, "/")
+	for i := range nodes {
+		if nodes[i].URLPath() == slugPath {
+			return &nodes[i]
+		}
+	}
+
+	for i := range nodes {
+		if nodes[i].Name == name {
+			return &nodes[i]
+		}
+	}
+	slug := slugify(name)
+	for i := range nodes {
+		if slugify(nodes[i].Name) == slug {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// findReference looks up a reference by display name, accepting the slug form too.
+func (c *Category) findReference(name string) *Reference {
+	if c == nil || name == "" {
+		return nil
+	}
+	for i := range c.References {
+		if c.References[i].Name == name {
+			return &c.References[i]
+		}
+	}
+	slug := slugify(name)
+	for i := range c.References {
+		if slugify(c.References[i].Name) == slug {
+			return &c.References[i]
+		}
+	}
+	return nil
+}
+
+// docCandidates lists the paths tried for a reference, relative to the
+// workspace root and in order. An explicit `file:` is the only candidate;
+// otherwise a few spellings of the name are tried inside the category folder.
+func docCandidates(folder string, ref Reference) []string {
+	if ref.File != "" {
+		return []string{filepath.FromSlash(ref.File)}
+	}
+
+	names := []string{
+		ref.Name,
+		strings.ReplaceAll(ref.Name, " ", "-"),
+		strings.ReplaceAll(ref.Name, " ", ""),
+		strings.ReplaceAll(ref.Name, "-", " "),
+		strings.ReplaceAll(ref.Name, "-", ""),
+	}
+
+	var candidates []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		candidates = append(candidates, filepath.Join(folder, name+".md"))
+	}
+	return candidates
+}
+
+// docNotFoundError reports every candidate tried while resolving a reference
+type docNotFoundError struct {
+	Category string
+	Name     string
+	Tried    []string
+}
+
+func (e *docNotFoundError) Error() string {
+	return fmt.Sprintf("no file for %q in category %q (tried %s)", e.Name, e.Category, strings.Join(e.Tried, ", "))
+}
+
+// resolveDoc returns the markdown file for a reference. Both the TUI and the
+// web server go through here so they always agree on which file is opened.
+// Only references declared in config resolve, and only to files inside
+// dataDir once symlinks are followed, as the web server passes in names
+// straight from the query string.
+func resolveDoc(config *Config, dataDir, catName, docName string) (string, error) {
+	cat := config.findCategory(catName)
+	if cat == nil {
+		return "", fmt.Errorf("no category %q", catName)
+	}
+	found := cat.findReference(docName)
+	if found == nil {
+		return "", fmt.Errorf("no reference %q in category %q", docName, cat.Key())
+	}
+	ref := *found
+
+	tried := docCandidates(cat.Dir, ref)
+	for _, rel := range tried {
+		fullPath := filepath.Join(dataDir, rel)
+		if info, err := os.Stat(fullPath); err == nil && info.Mode().IsRegular() {
+			if !insideDir(dataDir, fullPath) {
+				return "", fmt.Errorf("%s is outside the workspace", filepath.ToSlash(rel))
+			}
+			return fullPath, nil
+		}
+	}
+
+	return "", &docNotFoundError{Category: cat.Key(), Name: ref.Name, Tried: tried}
+}
+
+// insideDir reports whether path sits inside dir once both are resolved,
+// so neither ../ segments nor symlinks can reach outside of it
+func insideDir(dir, path string) bool {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under root, keyed by slash paths
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTraversalWorkspace lays out a workspace next to files it must never
+// serve: a `file: ../` reference and, where symlinks work, one escaping
+// through a link. It returns the workspace root.
+func newTraversalWorkspace(t *testing.T) string {
+	t.Helper()
+	parent := t.TempDir()
+	root := filepath.Join(parent, "docs")
+	writeFiles(t, parent, map[string]string{
+		"secret.md":        "# Secret\n",
+		"outside/Notes.md": "# Notes\n",
+		"docs/docs.yaml": `name: Docs
+categories:
+  - name: API
+    folder: api
+    references:
+      - name: Button
+      - name: Escape
+        file: ../secret.md
+      - name: Leak
+`,
+		"docs/README.md":        "# Docs\n",
+		"docs/api/Button.md":    "# Button\n![logo](logo.png)\n",
+		"docs/api/logo.png":     "png",
+		"docs/.git/config":      "[core]\n",
+		"docs/_drafts/Draft.md": "# Draft\n",
+	})
+	if err := os.Symlink(filepath.Join(parent, "outside", "Notes.md"), filepath.Join(root, "api", "Leak.md")); err != nil {
+		t.Logf("no symlink support: %v", err)
+	}
+	return root
+}
+
+func TestResolveDoc(t *testing.T) {
+	root := newTraversalWorkspace(t)
+	config, err := loadDocsConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := resolveDoc(config, root, "API", "Button")
+	if err != nil {
+		t.Fatalf("resolveDoc(API, Button): %v", err)
+	}
+	if want := filepath.Join(root, "api", "Button.md"); file != want {
+		t.Errorf("resolveDoc(API, Button) = %s, want %s", file, want)
+	}
+
+	tests := []struct {
+		cat, doc string
+		want     string // Part of the error
+	}{
+		{"../..", "Button", "no category"},
+		{"API", "../..", "no reference"},
+		{"API", "../../secret", "no reference"},
+		{"api/../..", "Button", "no category"},
+		{"API", "Escape", "outside the workspace"},
+		{"API", "Leak", "outside the workspace"},
+	}
+	for _, tt := range tests {
+		if tt.doc == "Leak" {
+			if _, err := os.Lstat(filepath.Join(root, "api", "Leak.md")); err != nil {
+				continue
+			}
+		}
+		_, err := resolveDoc(config, root, tt.cat, tt.doc)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("resolveDoc(%q, %q) error = %v, want %q", tt.cat, tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestInsideDir(t *testing.T) {
+	root := newTraversalWorkspace(t)
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "api", "Button.md"), true},
+		{filepath.Join(root, "api", "..", "README.md"), true},
+		{filepath.Join(root, "..", "secret.md"), false},
+		{filepath.Join(root, "api", "Missing.md"), false},
+	}
+	if _, err := os.Lstat(filepath.Join(root, "api", "Leak.md")); err == nil {
+		tests = append(tests, struct {
+			path string
+			want bool
+		}{filepath.Join(root, "api", "Leak.md"), false})
+	}
+	for _, tt := range tests {
+		if got := insideDir(root, tt.path); got != tt.want {
+			t.Errorf("insideDir(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
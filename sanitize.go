@@ -0,0 +1,55 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+// How raw HTML written inside markdown reaches the browser, set with
+// raw_html in docs.yaml
+const (
+	rawHTMLOmit     = "omit"     // Dropped, goldmark's default
+	rawHTMLSanitize = "sanitize" // Kept, then filtered through htmlPolicy
+	rawHTMLAllow    = "allow"    // Kept as is, for docs you fully trust
+)
+
+// rawHTMLMode returns the raw HTML handling of the open workspace,
+// unknown values falling back to omit
+func rawHTMLMode() string {
+	config := currentConfig.Load()
+	if config == nil {
+		return rawHTMLOmit
+	}
+	switch mode := strings.ToLower(config.RawHTML); mode {
+	case rawHTMLSanitize, rawHTMLAllow:
+		return mode
+	}
+	return rawHTMLOmit
+}
+
+// htmlPolicy is the allowlist applied to rendered documents in sanitize
+// mode: user generated content rules, plus the classes of highlighted code
+// and the data URI images of single-file exports. Scripts, event handlers,
+// iframes, forms and javascript: URLs are stripped.
+var htmlPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w -]+$`)).OnElements("pre", "code", "span", "div")
+	p.AllowDataURIImages()
+	return p
+}()
+
+// renderWebHTML converts a document to the HTML body of a web page
+func renderWebHTML(md goldmark.Markdown, source string) string {
+	var buf strings.Builder
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "<pre>" + html.EscapeString(source) + "</pre>"
+	}
+	if rawHTMLMode() == rawHTMLSanitize {
+		return htmlPolicy.Sanitize(buf.String())
+	}
+	return buf.String()
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// searchIndexVersion is bumped whenever the on-disk format or the tokenizer changes
+const searchIndexVersion = 2
+
+// BM25 tuning, the usual defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// searchIndex is an inverted index over every reference of a workspace. It
+// is persisted under the config dir and updated incrementally on load, so
+// only files whose mtime/size and content hash changed are re-tokenized.
+type searchIndex struct {
+	mu sync.RWMutex
+
+	Version  int                       `json:"version"`
+	Docs     map[string]*indexedDoc    `json:"docs"`     // Keyed by path relative to the workspace root
+	Postings map[string]map[string]int `json:"postings"` // Stemmed term -> doc path -> term frequency
+	TotalLen int                       `json:"total_len"`
+
+	refs map[string]string // itemKey -> doc path, rebuilt from the config on every load
+	root string            // Workspace root the doc paths are relative to
+	file string            // Where the index is persisted
+}
+
+type indexedDoc struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`
+	Length  int       `json:"length"` // Number of terms, for BM25 length normalisation
+	Terms   []string  `json:"terms"`  // Distinct terms, to drop postings on update
+}
+
+// searchHit is a document matching a query
+type searchHit struct {
+	score   float64
+	line    int    // 0-based line in the markdown source holding the snippet
+	snippet string // Text around the match
+	term    string // Matched word as written in the snippet line
+}
+
+// searchResult is one ranked document
+type searchResult struct {
+	path  string
+	score float64
+}
+
+// itemKey identifies a reference; names alone can repeat across categories
+func itemKey(category, name string) string {
+	return category + "\x00" + name
+}
+
+// splitItemKey returns the category and name an itemKey was built from
+func splitItemKey(key string) (category, name string) {
+	category, name, _ = strings.Cut(key, "\x00")
+	return category, name
+}
+
+// searchIndexFile returns where the index of a workspace is stored
+func searchIndexFile(dataDir string) string {
+	name := "workspace"
+	if currentWorkspace != nil && currentWorkspace.Name != "" {
+		name = slugify(currentWorkspace.Name)
+	}
+	sum := sha1.Sum([]byte(dataDir))
+	return filepath.Join(getConfigDir(), "index", name+"-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+// buildSearchIndex loads the persisted index of a workspace, brings it up to
+// date with the files on disk and saves it back if anything changed
+func buildSearchIndex(config *Config, dataDir string) *searchIndex {
+	idx := loadSearchIndex(searchIndexFile(dataDir))
+	if idx.update(config, dataDir) {
+		idx.save()
+	}
+	return idx
+}
+
+// loadSearchIndex reads an index from disk, starting empty if it is missing or outdated
+func loadSearchIndex(file string) *searchIndex {
+	idx := &searchIndex{}
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, idx)
+	}
+	if idx.Version != searchIndexVersion || idx.Docs == nil || idx.Postings == nil {
+		idx = &searchIndex{
+			Version:  searchIndexVersion,
+			Docs:     map[string]*indexedDoc{},
+			Postings: map[string]map[string]int{},
+		}
+	}
+	idx.file = file
+	idx.refs = map[string]string{}
+	return idx
+}
+
+// save writes the index atomically so a crash never leaves a truncated file
+func (idx *searchIndex) save() error {
+	idx.mu.RLock()
+	data, err := json.Marshal(idx)
+	idx.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.file), 0755); err != nil {
+		return err
+	}
+	tmp := idx.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.file)
+}
+
+// update re-indexes the references whose files changed and drops the ones
+// that are gone. It reports whether the index needs saving.
+func (idx *searchIndex) update(config *Config, dataDir string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	changed := false
+	seen := map[string]bool{}
+	idx.refs = map[string]string{}
+	idx.root = dataDir
+
+	for _, node := range config.walkCategories() {
+		for _, ref := range node.References {
+			path, err := resolveDoc(config, dataDir, node.Key(), ref.Name)
+			if err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(dataDir, path)
+			if err != nil {
+				continue
+			}
+			idx.refs[itemKey(node.Key(), ref.Name)] = rel
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			doc := idx.Docs[rel]
+			if doc != nil && doc.ModTime.Equal(info.ModTime()) && doc.Size == info.Size() {
+				continue
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			sum := sha256.Sum256(content)
+			hash := hex.EncodeToString(sum[:])
+			changed = true
+
+			// Touched but identical, only refresh the stat info
+			if doc != nil && doc.Hash == hash {
+				doc.ModTime = info.ModTime()
+				doc.Size = info.Size()
+				continue
+			}
+
+			idx.remove(rel)
+			idx.add(rel, string(content), info, hash)
+		}
+	}
+
+	for rel := range idx.Docs {
+		if !seen[rel] {
+			idx.remove(rel)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// add tokenizes a document into the postings; the caller holds the lock
+func (idx *searchIndex) add(rel, content string, info os.FileInfo, hash string) {
+	terms := tokenize(content)
+	freqs := map[string]int{}
+	for _, term := range terms {
+		freqs[term]++
+	}
+
+	doc := &indexedDoc{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Hash:    hash,
+		Length:  len(terms),
+	}
+	for term, n := range freqs {
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = map[string]int{}
+		}
+		idx.Postings[term][rel] = n
+		doc.Terms = append(doc.Terms, term)
+	}
+	sort.Strings(doc.Terms)
+
+	idx.Docs[rel] = doc
+	idx.TotalLen += doc.Length
+}
+
+// remove drops a document from the postings; the caller holds the lock
+func (idx *searchIndex) remove(rel string) {
+	doc, ok := idx.Docs[rel]
+	if !ok {
+		return
+	}
+	for _, term := range doc.Terms {
+		delete(idx.Postings[term], rel)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	idx.TotalLen -= doc.Length
+	delete(idx.Docs, rel)
+}
+
+// search ranks the documents containing every word of the query with BM25.
+// The last word is matched as a prefix so results follow the user's typing.
+func (idx *searchIndex) search(query string) []searchResult {
+	if idx == nil {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	words := tokenize(query)
+	if len(words) == 0 || len(idx.Docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.Docs))
+	avgLen := float64(idx.TotalLen) / n
+	scores := map[string]float64{}
+	matched := map[string]int{}
+
+	for i, word := range words {
+		terms := []string{word}
+		if i == len(words)-1 {
+			terms = idx.termsWithPrefix(word)
+		}
+
+		inDoc := map[string]bool{}
+		for _, term := range terms {
+			postings := idx.Postings[term]
+			df := float64(len(postings))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for path, tf := range postings {
+				docLen := float64(idx.Docs[path].Length)
+				f := float64(tf)
+				scores[path] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+				inDoc[path] = true
+			}
+		}
+		for path := range inDoc {
+			matched[path]++
+		}
+	}
+
+	var results []searchResult
+	for path, score := range scores {
+		if matched[path] == len(words) {
+			results = append(results, searchResult{path: path, score: score})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].path < results[j].path
+	})
+	return results
+}
+
+// termsWithPrefix lists the indexed terms starting with prefix; the caller holds the lock
+func (idx *searchIndex) termsWithPrefix(prefix string) []string {
+	var terms []string
+	for term := range idx.Postings {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// hits runs a search and maps the results back to references, keyed by itemKey
+func (idx *searchIndex) hits(query string) map[string]searchHit {
+	results := idx.search(query)
+	if len(results) == 0 {
+		return nil
+	}
+
+	byPath := map[string]searchHit{}
+	for _, r := range results {
+		hit := idx.snippet(r.path, query)
+		hit.score = r.score
+		byPath[r.path] = hit
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	hits := map[string]searchHit{}
+	for key, path := range idx.refs {
+		if hit, ok := byPath[path]; ok {
+			hits[key] = hit
+		}
+	}
+	return hits
+}
+
+// sourceLines reads the lines of an indexed document. The index keeps no
+// text, so snippets come from the file as it is now.
+func (idx *searchIndex) sourceLines(path string) []string {
+	idx.mu.RLock()
+	file := filepath.Join(idx.root, path)
+	idx.mu.RUnlock()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// snippet picks the first line of a document matching the most query words
+func (idx *searchIndex) snippet(path, query string) searchHit {
+	best := searchHit{line: -1}
+	words := tokenize(query)
+	bestCount := 0
+	for i, line := range idx.sourceLines(path) {
+		count := 0
+		term := ""
+		for _, raw := range splitWords(line) {
+			stemmed := stem(strings.ToLower(raw))
+			for j, word := range words {
+				if stemmed == word || (j == len(words)-1 && strings.HasPrefix(stemmed, word)) {
+					count++
+					if term == "" {
+						term = raw
+					}
+					break
+				}
+			}
+		}
+		if count > bestCount {
+			col := strings.Index(line, term)
+			best = searchHit{line: i, snippet: snippetAround(line, col, len(term)), term: term}
+			bestCount = count
+		}
+	}
+	return best
+}
+
+// lineCount returns the number of source lines of an indexed reference
+func (idx *searchIndex) lineCount(category, name string) int {
+	if idx == nil {
+		return 0
+	}
+	idx.mu.RLock()
+	path, ok := idx.refs[itemKey(category, name)]
+	idx.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	return len(idx.sourceLines(path))
+}
+
+// splitWords breaks text on anything that isn't a letter or a digit
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tokenize turns text into lowercased, stemmed terms
+func tokenize(text string) []string {
+	words := splitWords(text)
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, stem(strings.ToLower(word)))
+	}
+	return terms
+}
+
+// stemSuffixes are tried in order, longest first
+var stemSuffixes = []struct{ suffix, replace string }{
+	{"ational", "ate"},
+	{"ization", "ize"},
+	{"fulness", "ful"},
+	{"ousness", "ous"},
+	{"iveness", "ive"},
+	{"ations", "ate"},
+	{"ation", "ate"},
+	{"ments", ""},
+	{"ment", ""},
+	{"ings", ""},
+	{"ing", ""},
+	{"ies", "y"},
+	{"ied", "y"},
+	{"ers", ""},
+	{"er", ""},
+	{"ed", ""},
+	{"ly", ""},
+	{"es", ""},
+	{"s", ""},
+}
+
+// stem is a light suffix-stripping stemmer so "installing", "installed" and
+// "installs" all index as "install". Stems always keep at least 3 letters.
+func stem(word string) string {
+	if len(word) <= 3 || strings.HasSuffix(word, "ss") {
+		return word
+	}
+	for _, s := range stemSuffixes {
+		if strings.HasSuffix(word, s.suffix) && len(word)-len(s.suffix)+len(s.replace) >= 3 {
+			return word[:len(word)-len(s.suffix)] + s.replace
+		}
+	}
+	return word
+}
+
+// snippetMaxLen bounds the context kept around a match
+const snippetMaxLen = 80
+
+// snippetAround trims a matching line to the text surrounding the match
+func snippetAround(line string, col, length int) string {
+	if col < 0 {
+		col = 0
+	}
+	start := col - snippetMaxLen/3
+	prefix := "..."
+	if start <= 0 {
+		start = 0
+		prefix = ""
+	}
+	end := start + snippetMaxLen
+	if end < col+length {
+		end = col + length
+	}
+	suffix := "..."
+	if end >= len(line) {
+		end = len(line)
+		suffix = ""
+	}
+	// Keep byte offsets on rune boundaries
+	for start > 0 && !isRuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !isRuneStart(line[end]) {
+		end++
+	}
+	return prefix + strings.TrimSpace(line[start:end]) + suffix
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// renderedLineOf finds the matched word in glamour output so the viewport
+// can jump to it. The source line is mapped proportionally to get an
+// estimate, and the occurrence of the word closest to it wins.
+func renderedLineOf(rendered string, hit searchHit, sourceLines int) int {
+	lines := strings.Split(rendered, "\n")
+	estimate := 0
+	if sourceLines > 0 {
+		estimate = hit.line * len(lines) / sourceLines
+	}
+	if hit.term == "" {
+		return estimate
+	}
+
+	term := strings.ToLower(hit.term)
+	best, bestDist := estimate, -1
+	for i, line := range lines {
+		if !strings.Contains(strings.ToLower(ansiPattern.ReplaceAllString(line, "")), term) {
+			continue
+		}
+		dist := i - estimate
+		if dist < 0 {
+			dist = -dist
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
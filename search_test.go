@@ -0,0 +1,222 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assertIndexConsistent checks the bookkeeping update relies on: postings
+// and doc terms mirror each other and TotalLen sums the doc lengths
+func assertIndexConsistent(t *testing.T, idx *searchIndex) {
+	t.Helper()
+	total := 0
+	for rel, doc := range idx.Docs {
+		total += doc.Length
+		for _, term := range doc.Terms {
+			if idx.Postings[term][rel] == 0 {
+				t.Errorf("%s lists term %q without a posting", rel, term)
+			}
+		}
+	}
+	if idx.TotalLen != total {
+		t.Errorf("TotalLen = %d, want %d", idx.TotalLen, total)
+	}
+	for term, postings := range idx.Postings {
+		if len(postings) == 0 {
+			t.Errorf("empty postings kept for %q", term)
+		}
+		for rel := range postings {
+			if idx.Docs[rel] == nil {
+				t.Errorf("posting of %q points at %s, which isn't indexed", term, rel)
+			}
+		}
+	}
+}
+
+// assertSameScores compares an incrementally updated index with one built
+// from scratch over the same files
+func assertSameScores(t *testing.T, idx *searchIndex, config *Config, root string, queries ...string) {
+	t.Helper()
+	fresh := loadSearchIndex(filepath.Join(t.TempDir(), "fresh.json"))
+	fresh.update(config, root)
+	if idx.TotalLen != fresh.TotalLen || !reflect.DeepEqual(idx.Postings, fresh.Postings) {
+		t.Errorf("postings differ from a fresh index:\n got %v (total %d)\nwant %v (total %d)", idx.Postings, idx.TotalLen, fresh.Postings, fresh.TotalLen)
+	}
+	for _, q := range queries {
+		if got, want := idx.search(q), fresh.search(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("search(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+// setModTime gives a file a fixed mtime, so changes don't depend on the clock's resolution
+func setModTime(t *testing.T, file string, mtime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(file, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchIndexUpdate(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"guides/Alpha.md": "# Alpha\nInstalling the zebra widget.\n",
+		"guides/Beta.md":  "# Beta\nZebra zebra stripes, installed twice.\n",
+		"guides/Gamma.md": "# Gamma\nNothing to see.\n",
+	})
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		setModTime(t, filepath.Join(root, "guides", name+".md"), base)
+	}
+	config, err := loadDocsConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "index.json")
+	idx := loadSearchIndex(file)
+	if !idx.update(config, root) {
+		t.Fatal("first update reported no change")
+	}
+	assertIndexConsistent(t, idx)
+	alpha, beta := filepath.Join("guides", "Alpha.md"), filepath.Join("guides", "Beta.md")
+	if got := idx.Postings["install"]; got[alpha] != 1 || got[beta] != 1 {
+		t.Errorf("postings of install = %v, want 1 in Alpha and Beta", got)
+	}
+	results := idx.search("zebra")
+	if len(results) != 2 || results[0].path != beta || results[0].score <= results[1].score {
+		t.Errorf("search(zebra) = %v, want Beta ranked above Alpha", results)
+	}
+
+	// Persisted and loaded back, nothing needs re-indexing
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := loadSearchIndex(file)
+	if loaded.update(config, root) {
+		t.Error("update after reload reported a change")
+	}
+	if loaded.TotalLen != idx.TotalLen || !reflect.DeepEqual(loaded.Postings, idx.Postings) {
+		t.Error("reloaded index differs from the saved one")
+	}
+	idx = loaded
+
+	// Same mtime and size: the file isn't even read
+	alphaFile := filepath.Join(root, "guides", "Alpha.md")
+	writeFiles(t, root, map[string]string{"guides/Alpha.md": "# Alpha\nInstalling the koala widget.\n"})
+	setModTime(t, alphaFile, base)
+	if idx.update(config, root) {
+		t.Error("update re-read a file with unchanged mtime and size")
+	}
+
+	// Touched but identical: only the stat info is refreshed
+	writeFiles(t, root, map[string]string{"guides/Alpha.md": "# Alpha\nInstalling the zebra widget.\n"})
+	setModTime(t, alphaFile, base.Add(time.Hour))
+	doc := idx.Docs[alpha]
+	if !idx.update(config, root) {
+		t.Error("touch reported no change")
+	}
+	if idx.Docs[alpha] != doc || !doc.ModTime.Equal(base.Add(time.Hour)) {
+		t.Error("touch re-indexed the document instead of refreshing its mtime")
+	}
+	assertSameScores(t, idx, config, root, "zebra", "install")
+
+	// Modified: the old terms' postings go, the new ones come in
+	writeFiles(t, root, map[string]string{"guides/Alpha.md": "# Alpha\nA koala, a koala and a koala.\n"})
+	setModTime(t, alphaFile, base.Add(2*time.Hour))
+	if !idx.update(config, root) {
+		t.Error("modification reported no change")
+	}
+	if _, ok := idx.Postings["install"][alpha]; ok {
+		t.Error("stale posting of install kept for Alpha")
+	}
+	if idx.Postings["koala"][alpha] != 3 {
+		t.Errorf("postings of koala = %v, want 3 in Alpha", idx.Postings["koala"])
+	}
+	assertIndexConsistent(t, idx)
+	assertSameScores(t, idx, config, root, "zebra", "koala", "install", "alpha")
+
+	// Deleted: the document and its postings are dropped
+	if err := os.Remove(filepath.Join(root, "guides", "Beta.md")); err != nil {
+		t.Fatal(err)
+	}
+	config, err = loadDocsConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.update(config, root) {
+		t.Error("deletion reported no change")
+	}
+	if idx.Docs[beta] != nil {
+		t.Error("deleted Beta is still indexed")
+	}
+	if _, ok := idx.Postings["zebra"]; ok {
+		t.Errorf("postings of zebra = %v, want none left", idx.Postings["zebra"])
+	}
+	assertIndexConsistent(t, idx)
+	assertSameScores(t, idx, config, root, "zebra", "koala", "nothing")
+}
+
+func TestSearchIndexHits(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"api/Overview.md":    "# API\nThe widget endpoint.\n",
+		"guides/Overview.md": "# Guides\nNo match here.\n",
+	})
+	config, err := loadDocsConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := loadSearchIndex(filepath.Join(t.TempDir(), "index.json"))
+	idx.update(config, root)
+
+	// The last word matches as a prefix, hits are keyed by category and name
+	hits := idx.hits("widg")
+	hit, ok := hits[itemKey("Api", "Overview")]
+	if len(hits) != 1 || !ok {
+		t.Fatalf("hits(widg) = %v, want the Api Overview only", hits)
+	}
+	if hit.line != 1 || hit.term != "widget" || hit.snippet != "The widget endpoint." {
+		t.Errorf("hit = line %d term %q snippet %q, want line 1 term widget", hit.line, hit.term, hit.snippet)
+	}
+	if n := idx.lineCount("Api", "Overview"); n != 3 {
+		t.Errorf("lineCount = %d, want 3", n)
+	}
+}
+
+// The persisted index holds postings and stat data only, snippets are read
+// from the files as they are when searching
+func TestSearchIndexKeepsNoText(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"api/Overview.md": "# API\nThe widget endpoint.\n",
+	})
+	config, err := loadDocsConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "index.json")
+	idx := loadSearchIndex(file)
+	idx.update(config, root)
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "widget endpoint") {
+		t.Errorf("saved index holds the document text: %s", data)
+	}
+
+	idx = loadSearchIndex(file)
+	idx.update(config, root)
+	hit := idx.hits("widget")[itemKey("Api", "Overview")]
+	if hit.snippet != "The widget endpoint." {
+		t.Errorf("snippet after reload = %q, want the line read from the file", hit.snippet)
+	}
+}
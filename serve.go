@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish on exit
+const shutdownTimeout = 5 * time.Second
+
+// defaultWebAddr keeps the web preview local unless asked otherwise
+const defaultWebAddr = "127.0.0.1:8080"
+
+// webAddrFlag is the address given with --addr, it wins over the workspace's
+var webAddrFlag string
+
+// webAddr returns the address the web preview listens on: --addr, then the
+// workspace's addr, then defaultWebAddr. A bare port binds to 127.0.0.1.
+func webAddr() string {
+	addr := webAddrFlag
+	if addr == "" && currentWorkspace != nil {
+		addr = currentWorkspace.Addr
+	}
+	if addr == "" {
+		return defaultWebAddr
+	}
+	if !strings.Contains(addr, ":") {
+		return net.JoinHostPort("127.0.0.1", addr)
+	}
+	return addr
+}
+
+// listenWeb listens on addr, or on a free port of the same host when the
+// port is taken. fallback is set in that case.
+func listenWeb(addr string) (listener net.Listener, fallback bool, err error) {
+	listener, err = net.Listen("tcp", addr)
+	if err == nil || !addrInUse(err) {
+		return listener, false, err
+	}
+	host, _, splitErr := net.SplitHostPort(addr)
+	if splitErr != nil {
+		return nil, false, err
+	}
+	listener, err = net.Listen("tcp", net.JoinHostPort(host, "0"))
+	return listener, err == nil, err
+}
+
+// addrInUse tells a taken port from other listen errors. Windows fails with
+// WSAEADDRINUSE, which its syscall.EADDRINUSE doesn't match.
+func addrInUse(err error) bool {
+	const wsaeaddrinuse = syscall.Errno(10048)
+	return errors.Is(err, syscall.EADDRINUSE) || (runtime.GOOS == "windows" && errors.Is(err, wsaeaddrinuse))
+}
+
+// webURL is the address to browse to for a listener, localhost standing in
+// for an unspecified host like 0.0.0.0
+func webURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// runServe implements `efx-doc serve`: the web preview of a workspace served
+// without the TUI, e.g. as a shared docs server on a dev box or in a container
+func runServe(ws *Workspace) error {
+	config, warnings, err := openWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning: "+w)
+	}
+
+	// The root page is the welcome page
+	welcome := generateWelcomeContent(config, getDataDir())
+	page := generateFullPageHTML(config.Name, renderPreviewHTML(welcome, "", ""), "", "")
+	currentHTML.Store(&page)
+
+	addr := webAddr()
+	listener, fallback, err := listenWeb(addr)
+	if err != nil {
+		return err
+	}
+	if fallback {
+		fmt.Fprintf(os.Stderr, "%s is in use, using a free port instead\n", addr)
+	}
+
+	// Event streams never go idle on their own, so they are cancelled
+	// through the base context when shutting down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpServer = &http.Server{
+		Handler:     newWebMux(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	httpServer.RegisterOnShutdown(cancel)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", ws.Name, webURL(listener.Addr()))
+
+	select {
+	case err := <-served:
+		return err
+	case sig := <-stop:
+		fmt.Fprintf(os.Stderr, "Received %s, shutting down\n", sig)
+	}
+
+	shutdownCtx, done := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer done()
+	return httpServer.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/muesli/termenv"
+)
+
+// webTheme is the browser preview palette of a workspace, read from the
+// glamour-style JSON its styles.web points at, the same format as styles.tui
+type webTheme struct {
+	vars map[string]string // CSS variables overriding the built-in dark palette
+	css  string            // Rendered once by loadWebTheme
+}
+
+// currentWebTheme is the theme of the open workspace, nil for the built-in one
+var currentWebTheme *webTheme
+
+var defaultThemeCSS = codeCSS(styles.Get(darkCodeStyle))
+
+// CSS returns the rules the page inserts right after its built-in palette
+func (t *webTheme) CSS() string {
+	if t == nil {
+		return defaultThemeCSS
+	}
+	return t.css
+}
+
+// GetWebStylePath returns the path to the web style file, empty when the workspace has none
+func GetWebStylePath() string {
+	if currentWorkspace == nil || currentWorkspace.Styles.Web == "" {
+		return ""
+	}
+	return ExpandTilde(currentWorkspace.Styles.Web)
+}
+
+// loadWebTheme maps a glamour-style JSON theme onto the page's CSS variables.
+// Code blocks take the theme's chroma colours. Only the dark palette is
+// themed, the light toggle keeps the built-in light palette.
+func loadWebTheme(path string) (*webTheme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var style ansi.StyleConfig
+	if err := json.Unmarshal(data, &style); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	t := &webTheme{vars: map[string]string{}}
+	set := func(name string, colors ...*string) {
+		for _, c := range colors {
+			if css := cssColor(c); css != "" {
+				t.vars[name] = css
+				return
+			}
+		}
+	}
+	set("--bg", style.Document.BackgroundColor)
+	set("--fg", style.Document.Color, style.Text.Color, style.Paragraph.Color)
+	set("--heading", style.Heading.Color, style.H2.Color)
+	set("--accent", style.H1.BackgroundColor, style.Heading.Color)
+	set("--link", style.LinkText.Color, style.Link.Color)
+	set("--muted", style.BlockQuote.Color, style.HorizontalRule.Color)
+	set("--border", style.HorizontalRule.Color)
+	set("--code-fg", style.Code.Color)
+	set("--code-bg", style.Code.BackgroundColor)
+
+	code := styles.Get(darkCodeStyle)
+	if c := style.CodeBlock.Chroma; c != nil {
+		set("--pre-fg", style.CodeBlock.Color, c.Text.Color)
+		set("--pre-bg", style.CodeBlock.BackgroundColor, c.Background.BackgroundColor)
+		if themed, err := chromaStyleFromTheme(path, c); err == nil {
+			code = themed
+		}
+	} else {
+		set("--pre-fg", style.CodeBlock.Color)
+		set("--pre-bg", style.CodeBlock.BackgroundColor)
+	}
+
+	names := make([]string, 0, len(t.vars))
+	for name := range t.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("\t\t/* Workspace theme */\n\t\t:root {")
+	for _, name := range names {
+		sb.WriteString(" " + name + ": " + t.vars[name] + ";")
+	}
+	sb.WriteString(" }\n")
+	sb.WriteString(codeCSS(code))
+	t.css = sb.String()
+	return t, nil
+}
+
+// chromaStyleFromTheme builds a chroma style from the code_block.chroma
+// section of a theme, the way glamour does for the TUI
+func chromaStyleFromTheme(name string, c *ansi.Chroma) (*chroma.Style, error) {
+	entries := chroma.StyleEntries{}
+	for token, primitive := range map[chroma.TokenType]ansi.StylePrimitive{
+		chroma.Text:                c.Text,
+		chroma.Error:               c.Error,
+		chroma.Comment:             c.Comment,
+		chroma.CommentPreproc:      c.CommentPreproc,
+		chroma.Keyword:             c.Keyword,
+		chroma.KeywordReserved:     c.KeywordReserved,
+		chroma.KeywordNamespace:    c.KeywordNamespace,
+		chroma.KeywordType:         c.KeywordType,
+		chroma.Operator:            c.Operator,
+		chroma.Punctuation:         c.Punctuation,
+		chroma.Name:                c.Name,
+		chroma.NameBuiltin:         c.NameBuiltin,
+		chroma.NameTag:             c.NameTag,
+		chroma.NameAttribute:       c.NameAttribute,
+		chroma.NameClass:           c.NameClass,
+		chroma.NameConstant:        c.NameConstant,
+		chroma.NameDecorator:       c.NameDecorator,
+		chroma.NameException:       c.NameException,
+		chroma.NameFunction:        c.NameFunction,
+		chroma.NameOther:           c.NameOther,
+		chroma.Literal:             c.Literal,
+		chroma.LiteralNumber:       c.LiteralNumber,
+		chroma.LiteralDate:         c.LiteralDate,
+		chroma.LiteralString:       c.LiteralString,
+		chroma.LiteralStringEscape: c.LiteralStringEscape,
+		chroma.GenericDeleted:      c.GenericDeleted,
+		chroma.GenericEmph:         c.GenericEmph,
+		chroma.GenericInserted:     c.GenericInserted,
+		chroma.GenericStrong:       c.GenericStrong,
+		chroma.GenericSubheading:   c.GenericSubheading,
+		chroma.Background:          c.Background,
+	} {
+		if entry := chromaEntry(primitive); entry != "" {
+			entries[token] = entry
+		}
+	}
+	return chroma.NewStyle(name, entries)
+}
+
+// chromaEntry converts a style primitive to chroma's "#fff bg:#000 bold" notation
+func chromaEntry(p ansi.StylePrimitive) string {
+	var parts []string
+	if c := cssColor(p.Color); c != "" {
+		parts = append(parts, c)
+	}
+	if c := cssColor(p.BackgroundColor); c != "" {
+		parts = append(parts, "bg:"+c)
+	}
+	if p.Italic != nil && *p.Italic {
+		parts = append(parts, "italic")
+	}
+	if p.Bold != nil && *p.Bold {
+		parts = append(parts, "bold")
+	}
+	if p.Underline != nil && *p.Underline {
+		parts = append(parts, "underline")
+	}
+	return strings.Join(parts, " ")
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// cssColor converts a glamour colour, either "#rrggbb" or an ANSI 256 colour
+// number like "252", to CSS. Anything else gives "".
+func cssColor(c *string) string {
+	if c == nil || *c == "" {
+		return ""
+	}
+	if hexColor.MatchString(*c) {
+		return *c
+	}
+	if n, err := strconv.Atoi(*c); err == nil && n >= 0 && n < 256 {
+		return termenv.ConvertToRGB(termenv.ANSI256Color(n)).Hex()
+	}
+	return ""
+}
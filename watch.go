@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchInterval is how often the workspace is scanned for changes. Polling
+// keeps the watcher portable and dependency-free; a stat per file is cheap
+// even for workspaces with hundreds of documents.
+const watchInterval = time.Second
+
+// fileStamp is what a change is detected on
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// workspaceSnapshot maps every watched file to its stamp
+type workspaceSnapshot map[string]fileStamp
+
+// workspaceScanMsg carries a fresh snapshot back to the TUI
+type workspaceScanMsg struct {
+	dataDir  string
+	snapshot workspaceSnapshot
+}
+
+// snapshotWorkspace stats docs.yaml and every markdown file of the workspace
+func snapshotWorkspace(dataDir string) workspaceSnapshot {
+	snapshot := workspaceSnapshot{}
+	filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dataDir && skipDiscovery(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdown(d.Name()) && path != filepath.Join(dataDir, docsConfigName) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			snapshot[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return snapshot
+}
+
+// watchWorkspace scans the workspace after watchInterval
+func watchWorkspace(dataDir string) tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return workspaceScanMsg{dataDir: dataDir, snapshot: snapshotWorkspace(dataDir)}
+	})
+}
+
+// workspaceChanges lists files that were added, modified or removed between two snapshots
+type workspaceChanges struct {
+	changed  map[string]bool
+	manifest bool // docs.yaml changed, or files came and went
+}
+
+func diffSnapshots(dataDir string, prev, next workspaceSnapshot) workspaceChanges {
+	changes := workspaceChanges{changed: map[string]bool{}}
+	for path, stamp := range next {
+		old, ok := prev[path]
+		if !ok {
+			changes.manifest = true
+		}
+		if !ok || !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+			changes.changed[path] = true
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			changes.changed[path] = true
+			changes.manifest = true
+		}
+	}
+	if changes.changed[filepath.Join(dataDir, docsConfigName)] {
+		changes.manifest = true
+	}
+	return changes
+}